@@ -17,6 +17,9 @@ func main() {
 	}
 
 	go logfetcher.StartManager()
+	go logfetcher.StartSyslogServer()
+	go logfetcher.StartKafkaConsumer()
+	go logfetcher.StartFileTail()
 	go logexporter.StartDailyJobScheduler()
 
 	select {}
@@ -0,0 +1,37 @@
+// cmd/backfill/main.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"tedalogger-logfetcher/config"
+	"tedalogger-logfetcher/internal/logfetcher"
+)
+
+func main() {
+	nas := flag.String("nas", "", "arşivin ait olduğu NAS adı (örn. 10.0.0.1)")
+	brand := flag.String("brand", "", "NAS markası (boşsa API'den alınır)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Kullanım: backfill -nas 10.0.0.1 [-brand forti] dosya.log dosya.gz dosya.zst ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *nas == "" || flag.NArg() == 0 {
+		flag.Usage()
+		log.Fatalf("-nas ve en az bir dosya zorunlu")
+	}
+
+	if _, err := config.LoadConfig(); err != nil {
+		log.Fatalf("Config yüklenemedi: %v", err)
+	}
+
+	res, err := logfetcher.Backfill(*nas, *brand, flag.Args())
+	fmt.Printf("%d satır okundu, %d işlendi, %d başarısız.\n", res.Lines, res.Acked, res.Failed)
+	if err != nil {
+		log.Fatalf("Backfill hatası: %v", err)
+	}
+}
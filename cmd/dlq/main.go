@@ -0,0 +1,53 @@
+// cmd/dlq/main.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"tedalogger-logfetcher/config"
+	"tedalogger-logfetcher/internal/logfetcher"
+)
+
+func main() {
+	queue := flag.String("queue", "", "NAS kuyruğu (örn. forti-10.0.0.1-queue)")
+	action := flag.String("action", "inspect", "inspect ya da replay")
+	limit := flag.Int("limit", 20, "işlenecek en fazla mesaj sayısı (0 = hepsi)")
+	flag.Parse()
+
+	if *queue == "" {
+		log.Fatalf("-queue zorunlu")
+	}
+
+	if _, err := config.LoadConfig(); err != nil {
+		log.Fatalf("Config yüklenemedi: %v", err)
+	}
+
+	switch *action {
+	case "inspect":
+		letters, err := logfetcher.InspectDeadLetters(*queue, *limit)
+		if err != nil {
+			log.Fatalf("DLQ okunamadı: %v", err)
+		}
+		for i, dl := range letters {
+			fmt.Printf("#%d reason=%q failed_at=%s retries=%d\n", i+1, dl.Reason, dl.FailedAt, dl.RetryCount)
+			for _, d := range dl.Deaths {
+				fmt.Printf("    x-death queue=%v reason=%v count=%v\n", d["queue"], d["reason"], d["count"])
+			}
+			fmt.Printf("    %s\n", dl.Body)
+		}
+		fmt.Printf("%d mesaj listelendi.\n", len(letters))
+
+	case "replay":
+		n, err := logfetcher.ReplayDeadLetters(*queue, *limit)
+		if err != nil {
+			log.Fatalf("Replay hatası (%d mesaj aktarıldı): %v", n, err)
+		}
+		fmt.Printf("%d mesaj %s kuyruğuna geri aktarıldı.\n", n, *queue)
+
+	default:
+		log.Fatalf("Bilinmeyen action: %s", *action)
+	}
+}
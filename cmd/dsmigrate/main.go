@@ -0,0 +1,30 @@
+// cmd/dsmigrate/main.go
+
+package main
+
+import (
+	"flag"
+	"log"
+
+	"tedalogger-logfetcher/config"
+	"tedalogger-logfetcher/internal/logfetcher"
+)
+
+func main() {
+	pattern := flag.String("pattern", "", "taşınacak günlük indeks deseni (örn. 10_0_0_1-*)")
+	deleteSource := flag.Bool("delete-source", false, "kopya doğrulandıktan sonra eski indeksi sil ve adını data stream'e alias olarak bağla (hâlâ yazılabilen günler atlanır)")
+	flag.Parse()
+
+	if *pattern == "" {
+		log.Fatalf("-pattern zorunlu")
+	}
+
+	if _, err := config.LoadConfig(); err != nil {
+		log.Fatalf("Config yüklenemedi: %v", err)
+	}
+
+	if err := logfetcher.MigrateIndicesToDataStream(*pattern, *deleteSource); err != nil {
+		log.Fatalf("Data stream migration hatası: %v", err)
+	}
+	log.Println("Data stream migration tamamlandı.")
+}
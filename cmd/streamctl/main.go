@@ -0,0 +1,31 @@
+// cmd/streamctl/main.go
+
+package main
+
+import (
+	"flag"
+	"log"
+
+	"tedalogger-logfetcher/config"
+	"tedalogger-logfetcher/internal/logfetcher"
+)
+
+func main() {
+	queue := flag.String("queue", "", "stream kuyruğu (örn. forti-10.0.0.1-queue)")
+	consumer := flag.String("consumer", "", "tüketici adı (boşsa RABBITMQ_STREAM_CONSUMER_NAME)")
+	start := flag.String("start", "", "first, last, next, offset:N ya da timestamp:2026-01-02T15:04:05Z (broker deposunda next yok)")
+	flag.Parse()
+
+	if *queue == "" || *start == "" {
+		log.Fatalf("-queue ve -start zorunlu")
+	}
+
+	if _, err := config.LoadConfig(); err != nil {
+		log.Fatalf("Config yüklenemedi: %v", err)
+	}
+
+	if err := logfetcher.SetStreamPosition(*queue, *consumer, *start); err != nil {
+		log.Fatalf("Stream konumu ayarlanamadı: %v", err)
+	}
+	log.Println("Stream konumu güncellendi; çalışan tüketici bir sonraki commit'te yeniden başlayacak.")
+}
@@ -3,21 +3,204 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	RabbitMQURL string
+	RabbitMQURL      string
+	RabbitMQPrefetch int
+
+	RabbitMQDeadLetter         bool
+	RabbitMQRetryExchange      string
+	RabbitMQDeadLetterExchange string
+	RabbitMQRetryDelay         time.Duration
+	RabbitMQMaxRetries         int
+
+	RabbitMQReconnectMin time.Duration
+	RabbitMQReconnectMax time.Duration
+
+	RabbitMQChannelsPerConn int
+	RabbitMQConnectionName  string
+	RabbitMQCAFile          string
+	RabbitMQCertFile        string
+	RabbitMQKeyFile         string
+
+	RabbitMQDeclareQueues   bool
+	RabbitMQQueueType       string
+	RabbitMQExchange        string
+	RabbitMQExchangeType    string
+	RabbitMQRoutingKey      string
+	RabbitMQQueueMaxLength  int
+	RabbitMQQueueMessageTTL time.Duration
+	RabbitMQQueueOverflow   string
+	RabbitMQRetireMode      string
+	// Uzunluk ve TTL sınırlarını mevcut kuyruklara da uygulayan policy için yönetim API'si
+	RabbitMQManagementURL string
+	RabbitMQPolicyName    string
+
+	// auto | newline | octet | none: tek teslimatta birden çok kayıt nasıl ayrılır
+	RabbitMQBodyFraming string
+	RabbitMQMaxBodySize int
+
+	// Stream modunda tüketilecek kuyruk desenleri (örn. forti-*-queue)
+	RabbitMQStreamQueues       []string
+	RabbitMQStreamStart        string
+	RabbitMQStreamConsumerName string
+	RabbitMQStreamCommitEvery  int
+	// broker (stream protokolü StoreOffset/QueryOffset) ya da index (ES/OpenSearch)
+	RabbitMQStreamOffsetStore string
+	// Boşsa RABBITMQ_URL'in sunucusu, 5552 (amqps için 5551) portuyla kullanılır
+	RabbitMQStreamURL         string
+	RabbitMQStreamOffsetIndex string
+
+	SyslogUDPAddr     string
+	SyslogTCPAddr     string
+	SyslogTLSAddr     string
+	SyslogTLSCert     string
+	SyslogTLSKey      string
+	SyslogTLSCA       string
+	SyslogMaxMessage  int
+	SyslogWorkers     int
+	SyslogIdleTimeout time.Duration
+	SyslogMaxConns    int
+
+	KafkaBrokers     []string
+	KafkaTopics      []string
+	KafkaTopicsRegex bool
+	KafkaGroup       string
+	KafkaClientID    string
+	// topic ya da key: NAS adının kayıttan nasıl bulunacağı
+	KafkaNASFrom       string
+	KafkaTopicPrefix   string
+	KafkaTopicNAS      map[string]string
+	KafkaSASLMechanism string
+	KafkaSASLUser      string
+	KafkaSASLPass      string
+	KafkaTLS           bool
+	KafkaCAFile        string
+	KafkaCertFile      string
+	KafkaKeyFile       string
+	KafkaStart         string
+
+	// glob=NAS adı (örn. /var/log/remote/10.0.0.1/*.log=10.0.0.1)
+	FileTailPaths     map[string]string
+	FileTailPositions string
+	FileTailPoll      time.Duration
+	FileTailStart     string
 
 	APIBaseURL  string
 	APIUsername string
 	APIPassword string
 
-	ElasticURL  string
-	ElasticUser string
-	ElasticPass string
+	// elasticsearch ya da opensearch; Sinks boşsa tek hedef budur.
+	SinkType string
+
+	// Etkin sink adları ve yönlendirme kuralları ("koşul=sink+sink"), ilk eşleşen geçerli.
+	Sinks          []string
+	SinkRoutes     []string
+	SinkDefault    []string
+	SinkOptional   []string
+	SinkBufferSize int
+
+	// Virgülle ayrılmış düğüm adresleri; ElasticCloudID verilmişse boş olabilir.
+	ElasticURLs         []string
+	ElasticUser         string
+	ElasticPass         string
+	ElasticCloudID      string
+	ElasticAPIKey       string
+	ElasticServiceToken string
+
+	ElasticCAFile        string
+	ElasticFingerprint   string
+	ElasticCertFile      string
+	ElasticKeyFile       string
+	ElasticSniff         bool
+	ElasticSniffInterval time.Duration
+	ElasticCompress      bool
+
+	ElasticMaxRetries      int
+	ElasticRetryOnStatus   []string
+	ElasticRetryBackoff    time.Duration
+	ElasticRetryBackoffMax time.Duration
+	ElasticRequestTimeout  time.Duration
+
+	// OpenSearch bağlantısı. Yeniden deneme, zaman aşımı ve sıkıştırma ELASTIC_* ile ortaktır.
+	OpenSearchURLs        []string
+	OpenSearchUser        string
+	OpenSearchPass        string
+	OpenSearchCAFile      string
+	OpenSearchFingerprint string
+	OpenSearchCertFile    string
+	OpenSearchKeyFile     string
+
+	ESBulkFlushBytes    int
+	ESBulkFlushDocs     int
+	ESBulkFlushInterval time.Duration
+	ESBulkWorkers       int
+	ESBulkQueueSize     int
+
+	ESTemplatesInstall bool
+	ESTemplatePatterns []string
+	ESIndexShards      int
+	ESIndexReplicas    int
+	ESILMPolicy        string
+	ESILMWarmAfter     string
+	ESILMDeleteAfter   string
+
+	IndexTimezone   string
+	IndexMaxFuture  time.Duration
+	IndexMaxPast    time.Duration
+	IndexQuarantine string
+
+	// daily ya da datastream
+	ESIndexMode        string
+	ESDataStreamPrefix string
+	ESRolloverMaxSize  string
+	ESRolloverMaxAge   string
+	ESNASTenants       map[string]string
+
+	ESDeterministicIDs bool
+
+	ESSpoolDir           string
+	ESSpoolSegmentSize   int64
+	ESSpoolMaxSize       int64
+	ESSpoolFsync         string
+	ESSpoolFsyncInterval time.Duration
+
+	ESBulkRetries        int
+	ESBulkRetryMin       time.Duration
+	ESBulkRetryMax       time.Duration
+	ESBreakerThreshold   int
+	ESBreakerCooldown    time.Duration
+	ESBreakerMaxCooldown time.Duration
+
+	RabbitMQPauseOnBackpressure bool
+
+	AssetCSVDir       string
+	LDAPURL           string
+	LDAPBindDN        string
+	LDAPBindPass      string
+	LDAPBaseDN        string
+	LDAPUserFilter    string
+	LDAPStartTLS      bool
+	LDAPTimeout       time.Duration
+	LDAPFailureTTL    time.Duration
+	EnrichCacheTTL    time.Duration
+	EnrichNegCacheTTL time.Duration
+	EnrichCacheSize   int
+
+	// NAS adı -> zorlanan karakter seti (örn. 10.0.0.1=windows-1254)
+	NASCharsets map[string]string
+
+	// legacy ya da ecs; indeks deseni bazında IndexSchemas ile ezilebilir.
+	OutputSchema string
+	IndexSchemas []KeyValue
 }
 
 var cfg *Config
@@ -26,19 +209,249 @@ func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
 	cfg = &Config{
-		RabbitMQURL: getEnv("RABBITMQ_URL", ""),
+		RabbitMQURL:      getEnv("RABBITMQ_URL", ""),
+		RabbitMQPrefetch: getEnvInt("RABBITMQ_PREFETCH", 1000),
+
+		RabbitMQDeadLetter:         getEnvBool("RABBITMQ_DEAD_LETTER", true),
+		RabbitMQRetryExchange:      getEnv("RABBITMQ_RETRY_EXCHANGE", "tedalogger.retry"),
+		RabbitMQDeadLetterExchange: getEnv("RABBITMQ_DLX", "tedalogger.dlx"),
+		RabbitMQRetryDelay:         getEnvDuration("RABBITMQ_RETRY_DELAY", 30*time.Second),
+		RabbitMQMaxRetries:         getEnvInt("RABBITMQ_MAX_RETRIES", 5),
+
+		RabbitMQReconnectMin: getEnvDuration("RABBITMQ_RECONNECT_MIN", time.Second),
+		RabbitMQReconnectMax: getEnvDuration("RABBITMQ_RECONNECT_MAX", time.Minute),
+
+		RabbitMQChannelsPerConn: getEnvInt("RABBITMQ_CHANNELS_PER_CONN", 100),
+		RabbitMQConnectionName:  getEnv("RABBITMQ_CONNECTION_NAME", "tedalogger-logfetcher"),
+		RabbitMQCAFile:          getEnv("RABBITMQ_CA_FILE", ""),
+		RabbitMQCertFile:        getEnv("RABBITMQ_CERT_FILE", ""),
+		RabbitMQKeyFile:         getEnv("RABBITMQ_KEY_FILE", ""),
+
+		RabbitMQDeclareQueues:   getEnvBool("RABBITMQ_DECLARE_QUEUES", false),
+		RabbitMQQueueType:       getEnv("RABBITMQ_QUEUE_TYPE", "classic"),
+		RabbitMQExchange:        getEnv("RABBITMQ_EXCHANGE", ""),
+		RabbitMQExchangeType:    getEnv("RABBITMQ_EXCHANGE_TYPE", "topic"),
+		RabbitMQRoutingKey:      getEnv("RABBITMQ_ROUTING_KEY", "{brand}.{nas}"),
+		RabbitMQQueueMaxLength:  getEnvInt("RABBITMQ_QUEUE_MAX_LENGTH", 0),
+		RabbitMQQueueMessageTTL: getEnvDuration("RABBITMQ_QUEUE_MESSAGE_TTL", 0),
+		RabbitMQQueueOverflow:   getEnv("RABBITMQ_QUEUE_OVERFLOW", "reject-publish"),
+		RabbitMQRetireMode:      getEnv("RABBITMQ_RETIRE_MODE", "keep"),
+		RabbitMQManagementURL:   getEnv("RABBITMQ_MANAGEMENT_URL", ""),
+		RabbitMQPolicyName:      getEnv("RABBITMQ_POLICY_NAME", "tedalogger-nas-queues"),
+
+		RabbitMQBodyFraming: getEnv("RABBITMQ_BODY_FRAMING", "auto"),
+		RabbitMQMaxBodySize: getEnvInt("RABBITMQ_MAX_BODY_SIZE", 64<<20),
+
+		RabbitMQStreamQueues:       getEnvList("RABBITMQ_STREAM_QUEUES"),
+		RabbitMQStreamStart:        getEnv("RABBITMQ_STREAM_START", "next"),
+		RabbitMQStreamConsumerName: getEnv("RABBITMQ_STREAM_CONSUMER_NAME", "tedalogger-logfetcher"),
+		RabbitMQStreamCommitEvery:  getEnvInt("RABBITMQ_STREAM_COMMIT_EVERY", 500),
+		RabbitMQStreamOffsetStore:  getEnv("RABBITMQ_STREAM_OFFSET_STORE", "broker"),
+		RabbitMQStreamURL:          getEnv("RABBITMQ_STREAM_URL", ""),
+		RabbitMQStreamOffsetIndex:  getEnv("RABBITMQ_STREAM_OFFSET_INDEX", "tedalogger-stream-offsets"),
+
+		SyslogUDPAddr:     getEnv("SYSLOG_UDP_ADDR", ""),
+		SyslogTCPAddr:     getEnv("SYSLOG_TCP_ADDR", ""),
+		SyslogTLSAddr:     getEnv("SYSLOG_TLS_ADDR", ""),
+		SyslogTLSCert:     getEnv("SYSLOG_TLS_CERT", ""),
+		SyslogTLSKey:      getEnv("SYSLOG_TLS_KEY", ""),
+		SyslogTLSCA:       getEnv("SYSLOG_TLS_CA", ""),
+		SyslogMaxMessage:  getEnvInt("SYSLOG_MAX_MESSAGE", 64*1024),
+		SyslogWorkers:     getEnvInt("SYSLOG_WORKERS", 8),
+		SyslogIdleTimeout: getEnvDuration("SYSLOG_IDLE_TIMEOUT", 5*time.Minute),
+		SyslogMaxConns:    getEnvInt("SYSLOG_MAX_CONNS", 1000),
+
+		KafkaBrokers:       getEnvList("KAFKA_BROKERS"),
+		KafkaTopics:        getEnvList("KAFKA_TOPICS"),
+		KafkaTopicsRegex:   getEnvBool("KAFKA_TOPICS_REGEX", false),
+		KafkaGroup:         getEnv("KAFKA_GROUP", "tedalogger-logfetcher"),
+		KafkaClientID:      getEnv("KAFKA_CLIENT_ID", "tedalogger-logfetcher"),
+		KafkaNASFrom:       getEnv("KAFKA_NAS_FROM", "topic"),
+		KafkaTopicPrefix:   getEnv("KAFKA_TOPIC_PREFIX", ""),
+		KafkaTopicNAS:      getEnvMap("KAFKA_TOPIC_NAS"),
+		KafkaSASLMechanism: getEnv("KAFKA_SASL_MECHANISM", ""),
+		KafkaSASLUser:      getEnv("KAFKA_SASL_USER", ""),
+		KafkaSASLPass:      getEnv("KAFKA_SASL_PASS", ""),
+		KafkaTLS:           getEnvBool("KAFKA_TLS", false),
+		KafkaCAFile:        getEnv("KAFKA_CA_FILE", ""),
+		KafkaCertFile:      getEnv("KAFKA_CERT_FILE", ""),
+		KafkaKeyFile:       getEnv("KAFKA_KEY_FILE", ""),
+		KafkaStart:         getEnv("KAFKA_START", "earliest"),
+
+		FileTailPaths:     getEnvMap("FILE_TAIL_PATHS"),
+		FileTailPositions: getEnv("FILE_TAIL_POSITIONS", "tail-positions.json"),
+		FileTailPoll:      getEnvDuration("FILE_TAIL_POLL", time.Second),
+		FileTailStart:     getEnv("FILE_TAIL_START", "end"),
 
 		APIBaseURL:  getEnv("API_BASE_URL", ""),
 		APIUsername: getEnv("API_USERNAME", ""),
 		APIPassword: getEnv("API_PASSWORD", ""),
 
-		ElasticURL:  getEnv("ELASTIC_URL", ""),
-		ElasticUser: getEnv("ELASTIC_USER", ""),
-		ElasticPass: getEnv("ELASTIC_PASS", ""),
+		SinkType: getEnv("SINK_TYPE", "elasticsearch"),
+
+		Sinks:          getEnvList("SINKS"),
+		SinkRoutes:     getEnvList("SINK_ROUTES"),
+		SinkDefault:    getEnvList("SINK_DEFAULT"),
+		SinkOptional:   getEnvList("SINK_OPTIONAL"),
+		SinkBufferSize: getEnvInt("SINK_BUFFER_SIZE", 10000),
+
+		ElasticURLs:         getEnvList("ELASTIC_URL"),
+		ElasticUser:         getEnv("ELASTIC_USER", ""),
+		ElasticPass:         getEnv("ELASTIC_PASS", ""),
+		ElasticCloudID:      getEnv("ELASTIC_CLOUD_ID", ""),
+		ElasticAPIKey:       getEnv("ELASTIC_API_KEY", ""),
+		ElasticServiceToken: getEnv("ELASTIC_SERVICE_TOKEN", ""),
+
+		ElasticCAFile:        getEnv("ELASTIC_CA_FILE", ""),
+		ElasticFingerprint:   getEnv("ELASTIC_CA_FINGERPRINT", ""),
+		ElasticCertFile:      getEnv("ELASTIC_CERT_FILE", ""),
+		ElasticKeyFile:       getEnv("ELASTIC_KEY_FILE", ""),
+		ElasticSniff:         getEnvBool("ELASTIC_SNIFF", false),
+		ElasticSniffInterval: getEnvDuration("ELASTIC_SNIFF_INTERVAL", 5*time.Minute),
+		ElasticCompress:      getEnvBool("ELASTIC_COMPRESS", false),
+
+		ElasticMaxRetries:      getEnvInt("ELASTIC_MAX_RETRIES", 3),
+		ElasticRetryOnStatus:   getEnvList("ELASTIC_RETRY_ON_STATUS"),
+		ElasticRetryBackoff:    getEnvDuration("ELASTIC_RETRY_BACKOFF", 100*time.Millisecond),
+		ElasticRetryBackoffMax: getEnvDuration("ELASTIC_RETRY_BACKOFF_MAX", 5*time.Second),
+		ElasticRequestTimeout:  getEnvDuration("ELASTIC_REQUEST_TIMEOUT", 60*time.Second),
+
+		OpenSearchURLs:        getEnvList("OPENSEARCH_URL"),
+		OpenSearchUser:        getEnv("OPENSEARCH_USER", ""),
+		OpenSearchPass:        getEnv("OPENSEARCH_PASS", ""),
+		OpenSearchCAFile:      getEnv("OPENSEARCH_CA_FILE", ""),
+		OpenSearchFingerprint: getEnv("OPENSEARCH_CA_FINGERPRINT", ""),
+		OpenSearchCertFile:    getEnv("OPENSEARCH_CERT_FILE", ""),
+		OpenSearchKeyFile:     getEnv("OPENSEARCH_KEY_FILE", ""),
+
+		ESBulkFlushBytes:    getEnvInt("ES_BULK_FLUSH_BYTES", 5<<20),
+		ESBulkFlushDocs:     getEnvInt("ES_BULK_FLUSH_DOCS", 1000),
+		ESBulkFlushInterval: getEnvDuration("ES_BULK_FLUSH_INTERVAL", time.Second),
+		ESBulkWorkers:       getEnvInt("ES_BULK_WORKERS", 2),
+		ESBulkQueueSize:     getEnvInt("ES_BULK_QUEUE_SIZE", 10000),
+
+		ESTemplatesInstall: getEnvBool("ES_TEMPLATES_INSTALL", true),
+		ESTemplatePatterns: getEnvList("ES_TEMPLATE_PATTERNS"),
+		ESIndexShards:      getEnvInt("ES_INDEX_SHARDS", 1),
+		ESIndexReplicas:    getEnvInt("ES_INDEX_REPLICAS", 1),
+		ESILMPolicy:        getEnv("ES_ILM_POLICY", "tedalogger-urlfilter"),
+		ESILMWarmAfter:     getEnv("ES_ILM_WARM_AFTER", "7d"),
+		ESILMDeleteAfter:   getEnv("ES_ILM_DELETE_AFTER", "730d"),
+
+		IndexTimezone:   getEnv("INDEX_TIMEZONE", "Local"),
+		IndexMaxFuture:  getEnvDuration("INDEX_MAX_FUTURE", 2*time.Hour),
+		IndexMaxPast:    getEnvDuration("INDEX_MAX_PAST", 6*24*time.Hour),
+		IndexQuarantine: getEnv("INDEX_QUARANTINE", "tedalogger-quarantine"),
+
+		ESIndexMode:        getEnv("ES_INDEX_MODE", "daily"),
+		ESDataStreamPrefix: getEnv("ES_DATASTREAM_PREFIX", "logs-urlfilter-"),
+		ESRolloverMaxSize:  getEnv("ES_ROLLOVER_MAX_SIZE", "50gb"),
+		ESRolloverMaxAge:   getEnv("ES_ROLLOVER_MAX_AGE", "1d"),
+		ESNASTenants:       getEnvMap("ES_NAS_TENANTS"),
+
+		ESDeterministicIDs: getEnvBool("ES_DETERMINISTIC_IDS", false),
+
+		ESSpoolDir:           getEnv("ES_SPOOL_DIR", ""),
+		ESSpoolSegmentSize:   int64(getEnvInt("ES_SPOOL_SEGMENT_SIZE", 64<<20)),
+		ESSpoolMaxSize:       int64(getEnvInt("ES_SPOOL_MAX_SIZE", 1<<30)),
+		ESSpoolFsync:         getEnv("ES_SPOOL_FSYNC", "interval"),
+		ESSpoolFsyncInterval: getEnvDuration("ES_SPOOL_FSYNC_INTERVAL", time.Second),
+
+		ESBulkRetries:        getEnvInt("ES_BULK_RETRIES", 3),
+		ESBulkRetryMin:       getEnvDuration("ES_BULK_RETRY_MIN", 500*time.Millisecond),
+		ESBulkRetryMax:       getEnvDuration("ES_BULK_RETRY_MAX", 30*time.Second),
+		ESBreakerThreshold:   getEnvInt("ES_BREAKER_THRESHOLD", 3),
+		ESBreakerCooldown:    getEnvDuration("ES_BREAKER_COOLDOWN", 5*time.Second),
+		ESBreakerMaxCooldown: getEnvDuration("ES_BREAKER_MAX_COOLDOWN", 2*time.Minute),
+
+		RabbitMQPauseOnBackpressure: getEnvBool("RABBITMQ_PAUSE_ON_BACKPRESSURE", true),
+
+		AssetCSVDir:       getEnv("ASSET_CSV_DIR", ""),
+		LDAPURL:           getEnv("LDAP_URL", ""),
+		LDAPBindDN:        getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPass:      getEnv("LDAP_BIND_PASS", ""),
+		LDAPBaseDN:        getEnv("LDAP_BASE_DN", ""),
+		LDAPUserFilter:    getEnv("LDAP_USER_FILTER", "(|(uid=%[1]s)(sAMAccountName=%[1]s))"),
+		LDAPStartTLS:      getEnvBool("LDAP_STARTTLS", false),
+		LDAPTimeout:       getEnvDuration("LDAP_TIMEOUT", 2*time.Second),
+		LDAPFailureTTL:    getEnvDuration("LDAP_FAILURE_TTL", 30*time.Second),
+		EnrichCacheTTL:    getEnvDuration("ENRICH_CACHE_TTL", 15*time.Minute),
+		EnrichNegCacheTTL: getEnvDuration("ENRICH_NEG_CACHE_TTL", 2*time.Minute),
+		EnrichCacheSize:   getEnvInt("ENRICH_CACHE_SIZE", 10000),
+
+		NASCharsets: getEnvMap("NAS_CHARSETS"),
+
+		OutputSchema: getEnv("OUTPUT_SCHEMA", "legacy"),
+		IndexSchemas: getEnvPairs("ES_INDEX_SCHEMAS"),
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
+// validate birbiriyle çelişen ayarları reddeder.
+func (c *Config) validate() error {
+	// Bekleme süresi 0 olursa iki katına çıkarılsa da 0 kalır; devre her istekte açılıp kapanır.
+	if c.ESBreakerCooldown <= 0 {
+		return fmt.Errorf("ES_BREAKER_COOLDOWN must be positive, got %s", c.ESBreakerCooldown)
+	}
+	if c.ESBreakerMaxCooldown < c.ESBreakerCooldown {
+		return fmt.Errorf("ES_BREAKER_MAX_COOLDOWN (%s) must not be shorter than ES_BREAKER_COOLDOWN (%s)",
+			c.ESBreakerMaxCooldown, c.ESBreakerCooldown)
+	}
+
+	switch strings.ToLower(c.RabbitMQStreamOffsetStore) {
+	case "", "broker", "index":
+	default:
+		return fmt.Errorf("RABBITMQ_STREAM_OFFSET_STORE must be broker or index, got %q", c.RabbitMQStreamOffsetStore)
+	}
+
+	// Günlük indeks, ilk belgesi yazıldığında açılır ve ES_ILM_WARM_AFTER sonra salt okunur
+	// olur. Olay zamanına göre yönlendirilen geç bir belge gününün indeksine ancak o gün
+	// salt okunur olmadan yazılabilir; aksi halde 403 alıp DLQ'ya düşer. İndeks günün başında
+	// açılmış olabileceğinden bir günlük pay bırakılır. Data stream'lerde yazım her zaman
+	// güncel indekse yapılır.
+	if c.ESTemplatesInstall && c.ESILMWarmAfter != "" && c.IndexMaxPast > 0 &&
+		!strings.EqualFold(c.ESIndexMode, "datastream") {
+		warm, err := parseESDuration(c.ESILMWarmAfter)
+		if err != nil {
+			return fmt.Errorf("invalid ES_ILM_WARM_AFTER: %w", err)
+		}
+		if c.IndexMaxPast+24*time.Hour > warm {
+			return fmt.Errorf("INDEX_MAX_PAST (%s) must be at least one day shorter than ES_ILM_WARM_AFTER (%s)",
+				c.IndexMaxPast, c.ESILMWarmAfter)
+		}
+	}
+	return nil
+}
+
+// parseESDuration ES zaman birimlerini (7d, 12h, 30m) çözer.
+func parseESDuration(v string) (time.Duration, error) {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"nanos", time.Nanosecond},
+		{"micros", time.Microsecond},
+		{"ms", time.Millisecond},
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(v, u.suffix); ok {
+			i, err := strconv.ParseInt(n, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("%q is not an ES time value", v)
+			}
+			return time.Duration(i) * u.unit, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not an ES time value", v)
+}
+
 func GetConfig() *Config {
 	return cfg
 }
@@ -50,3 +463,88 @@ func getEnv(key, fallback string) string {
 	}
 	return val
 }
+
+func getEnvInt(key string, fallback int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// getEnvList virgülle ayrılmış değeri boş öğeler atılarak döndürür.
+func getEnvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// KeyValue sırası anlamlı olan ayarların (ilk eşleşen kazanır) bir öğesidir.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// getEnvPairs "k1=v1,k2=v2" biçimindeki değeri yazıldığı sırayla döndürür.
+func getEnvPairs(key string) []KeyValue {
+	var out []KeyValue
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k != "" && v != "" {
+			out = append(out, KeyValue{Key: k, Value: v})
+		}
+	}
+	return out
+}
+
+// getEnvMap "k1=v1,k2=v2" biçimindeki değeri map olarak döndürür.
+func getEnvMap(key string) map[string]string {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k != "" && v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateIndexMaxPast(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"past within warm", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, ESILMWarmAfter: "7d", IndexMaxPast: 6 * 24 * time.Hour}, false},
+		{"past reaches warm", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, ESILMWarmAfter: "7d", IndexMaxPast: 30 * 24 * time.Hour}, true},
+		{"within last day of warm", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, ESILMWarmAfter: "7d", IndexMaxPast: 6*24*time.Hour + time.Minute}, true},
+		{"no limit", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, ESILMWarmAfter: "7d"}, false},
+		{"no warm phase", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, IndexMaxPast: 30 * 24 * time.Hour}, false},
+		{"nas derived patterns", Config{ESTemplatesInstall: true, ESILMWarmAfter: "7d", IndexMaxPast: 30 * 24 * time.Hour}, true},
+		{"templates not installed", Config{ESILMWarmAfter: "7d", IndexMaxPast: 30 * 24 * time.Hour}, false},
+		{"data streams", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, ESIndexMode: "datastream", ESILMWarmAfter: "7d", IndexMaxPast: 30 * 24 * time.Hour}, false},
+		{"bad warm value", Config{ESTemplatesInstall: true, ESTemplatePatterns: []string{"10_*-*"}, ESILMWarmAfter: "a week", IndexMaxPast: time.Hour}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ESBreakerCooldown, tt.cfg.ESBreakerMaxCooldown = 5*time.Second, 2*time.Minute
+			if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBreakerCooldown(t *testing.T) {
+	tests := []struct {
+		name          string
+		cooldown, max time.Duration
+		wantErr       bool
+	}{
+		{"defaults", 5 * time.Second, 2 * time.Minute, false},
+		{"fixed cooldown", time.Minute, time.Minute, false},
+		{"zero cooldown", 0, 2 * time.Minute, true},
+		{"negative cooldown", -time.Second, 2 * time.Minute, true},
+		{"max below cooldown", time.Minute, time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{ESBreakerCooldown: tt.cooldown, ESBreakerMaxCooldown: tt.max}
+			if err := c.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStreamOffsetStore(t *testing.T) {
+	for store, wantErr := range map[string]bool{"broker": false, "index": false, "Broker": false, "redis": true} {
+		c := Config{ESBreakerCooldown: time.Second, ESBreakerMaxCooldown: time.Minute, RabbitMQStreamOffsetStore: store}
+		if err := c.validate(); (err != nil) != wantErr {
+			t.Errorf("validate(%q) = %v, wantErr %v", store, err, wantErr)
+		}
+	}
+}
+
+func TestParseESDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"7d":    7 * 24 * time.Hour,
+		"12h":   12 * time.Hour,
+		"30m":   30 * time.Minute,
+		"45s":   45 * time.Second,
+		"500ms": 500 * time.Millisecond,
+	}
+	for in, want := range tests {
+		got, err := parseESDuration(in)
+		if err != nil || got != want {
+			t.Errorf("parseESDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseESDuration("7w"); err == nil {
+		t.Error("parseESDuration(7w) succeeded")
+	}
+}
+
+// TestLoadConfigDefaults ortam boşken varsayılan ayarların birbiriyle uyumlu olduğunu sınar.
+func TestLoadConfigDefaults(t *testing.T) {
+	for _, key := range []string{"ES_TEMPLATES_INSTALL", "ES_TEMPLATE_PATTERNS", "ES_ILM_WARM_AFTER", "INDEX_MAX_PAST", "ES_INDEX_MODE"} {
+		t.Setenv(key, "")
+	}
+	c, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.ESTemplatesInstall || c.ESILMWarmAfter != "7d" || c.IndexMaxPast != 6*24*time.Hour || len(c.ESTemplatePatterns) != 0 {
+		t.Fatalf("unexpected defaults: install=%v warm=%s past=%s patterns=%v",
+			c.ESTemplatesInstall, c.ESILMWarmAfter, c.IndexMaxPast, c.ESTemplatePatterns)
+	}
+}
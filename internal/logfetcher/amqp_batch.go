@@ -0,0 +1,162 @@
+// internal/logfetcher/amqp_batch.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// errBodyTooLarge açılmış gövde RABBITMQ_MAX_BODY_SIZE'ı aştığında döner.
+var errBodyTooLarge = errors.New("decompressed body exceeds RABBITMQ_MAX_BODY_SIZE")
+
+// snappyStreamMagic framed snappy akışının başlığıdır; yoksa gövde tek blok kabul edilir.
+var snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+// decodeContentEncoding AMQP ContentEncoding'e göre gövdeyi açar.
+func decodeContentEncoding(body []byte, encoding string, maxSize int) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("gzip error: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("zstd error: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "snappy", "x-snappy-framed":
+		if !bytes.HasPrefix(body, snappyStreamMagic) {
+			n, err := snappy.DecodedLen(body)
+			if err != nil {
+				return nil, fmt.Errorf("snappy error: %w", err)
+			}
+			if n > maxSize {
+				return nil, errBodyTooLarge
+			}
+			return snappy.Decode(nil, body)
+		}
+		r = snappy.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	out, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s decode error: %w", encoding, err)
+	}
+	if len(out) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	return out, nil
+}
+
+// splitBody bir teslimattaki kayıtları ayırır. auto modunda gövde baştan sona geçerli
+// RFC 6587 octet-counting çerçevelerinden oluşuyorsa öyle, değilse satır satır bölünür.
+func splitBody(body []byte, framing string) [][]byte {
+	switch framing {
+	case "none":
+		return [][]byte{body}
+	case "octet":
+		if recs, ok := splitOctetCounted(body); ok {
+			return recs
+		}
+		return [][]byte{body}
+	case "newline":
+		return splitLines(body)
+	default:
+		if recs, ok := splitOctetCounted(body); ok {
+			return recs
+		}
+		return splitLines(body)
+	}
+}
+
+func splitOctetCounted(body []byte) ([][]byte, bool) {
+	var out [][]byte
+	for {
+		// Bazı göndericiler çerçeveler arasına satır sonu koyuyor.
+		body = bytes.TrimLeft(body, "\r\n")
+		if len(body) == 0 {
+			break
+		}
+		sp := bytes.IndexByte(body, ' ')
+		if sp <= 0 || sp > 10 {
+			return nil, false
+		}
+		n, err := strconv.Atoi(string(body[:sp]))
+		if err != nil || n <= 0 || sp+1+n > len(body) {
+			return nil, false
+		}
+		out = append(out, body[sp+1:sp+1+n])
+		body = body[sp+1+n:]
+	}
+	return out, len(out) > 0
+}
+
+func splitLines(body []byte) [][]byte {
+	var out [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r\x00")
+		if len(line) > 0 {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// batchAck bir teslimattaki kayıtların sonuçlarını toplar ve hepsi bittiğinde done'ı
+// bir kez çağırır. Kayıtlardan biri indekslenemediyse teslimat bütün olarak reddedilir;
+// geçici hatada teslimat yeniden geldiğinde başarıyla yazılmış kayıtlar da tekrar
+// işlenir. Bunların ikinci kez yazılmaması için toplu teslimatın kayıtları konumdan
+// türetilen kimlikle yazılır (SourceMessage.RequireID); konumu olmayan (message-id'siz)
+// toplu teslimatlarda kimlik üretilemez ve bu kayıtlar çift yazılır.
+type batchAck struct {
+	mu        sync.Mutex
+	remaining int
+	err       error
+	retryable bool
+	done      func(err error, retryable bool)
+}
+
+func newBatchAck(n int, done func(err error, retryable bool)) *batchAck {
+	return &batchAck{remaining: n, done: done}
+}
+
+func (b *batchAck) ack() { b.finish(nil, false) }
+
+func (b *batchAck) nack(err error, retryable bool) { b.finish(err, retryable) }
+
+func (b *batchAck) finish(err error, retryable bool) {
+	b.mu.Lock()
+	if err != nil {
+		if b.err == nil || (retryable && !b.retryable) {
+			// Geçici hata varsa teslimat yeniden denensin; kalıcı hata DLQ'ya gitsin.
+			b.err, b.retryable = err, retryable
+		}
+	}
+	b.remaining--
+	last := b.remaining == 0
+	b.mu.Unlock()
+
+	if last {
+		b.done(b.err, b.retryable)
+	}
+}
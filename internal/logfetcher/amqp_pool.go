@@ -0,0 +1,185 @@
+// internal/logfetcher/amqp_pool.go
+
+package logfetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+	"tedalogger-logfetcher/config"
+)
+
+// amqpPool kuyruk tüketicilerini az sayıda TCP bağlantısı üzerinde çoklar; her kuyruk
+// kendi kanalını alır. Bir bağlantı dolduğunda (channelsPerConn) yenisi açılır.
+type amqpPool struct {
+	channelsPerConn int
+
+	mu     sync.Mutex
+	conns  []*pooledConn
+	nextID int
+}
+
+type pooledConn struct {
+	id       int
+	conn     *amqp.Connection
+	channels int
+	closed   chan *amqp.Error
+}
+
+type pooledChannel struct {
+	ch   *amqp.Channel
+	pc   *pooledConn
+	pool *amqpPool
+	once sync.Once
+}
+
+var (
+	amqpPoolOnce sync.Once
+	amqpPoolInst *amqpPool
+)
+
+func getAMQPPool() *amqpPool {
+	amqpPoolOnce.Do(func() {
+		n := config.GetConfig().RabbitMQChannelsPerConn
+		if n <= 0 {
+			n = 100
+		}
+		amqpPoolInst = &amqpPool{channelsPerConn: n}
+	})
+	return amqpPoolInst
+}
+
+func (pc *pooledConn) isClosed() bool {
+	select {
+	case <-pc.closed:
+		return true
+	default:
+		return pc.conn.IsClosed()
+	}
+}
+
+// acquire kapasitesi olan canlı bir bağlantıda yeni kanal açar. Kapanmış bağlantılar
+// bu sırada havuzdan atılır.
+func (p *amqpPool) acquire() (*pooledChannel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	live := p.conns[:0]
+	for _, pc := range p.conns {
+		if !pc.isClosed() {
+			live = append(live, pc)
+		}
+	}
+	p.conns = live
+
+	var target *pooledConn
+	for _, pc := range p.conns {
+		if pc.channels < p.channelsPerConn && (target == nil || pc.channels < target.channels) {
+			target = pc
+		}
+	}
+
+	if target == nil {
+		p.nextID++
+		name := fmt.Sprintf("%s-%d", config.GetConfig().RabbitMQConnectionName, p.nextID)
+		conn, err := dialAMQP(name)
+		if err != nil {
+			return nil, err
+		}
+		target = &pooledConn{
+			id:     p.nextID,
+			conn:   conn,
+			closed: conn.NotifyClose(make(chan *amqp.Error, 1)),
+		}
+		p.conns = append(p.conns, target)
+		log.Printf("AMQP connection %s opened (pool size=%d)", name, len(p.conns))
+	}
+
+	ch, err := target.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("Channel error: %w", err)
+	}
+	target.channels++
+	return &pooledChannel{ch: ch, pc: target, pool: p}, nil
+}
+
+// release kanalı kapatır. Boşalan bağlantı, havuzdaki tek bağlantı değilse kapatılır.
+func (c *pooledChannel) release() {
+	c.once.Do(func() {
+		c.ch.Close()
+
+		p := c.pool
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		c.pc.channels--
+		if c.pc.channels > 0 || len(p.conns) <= 1 {
+			return
+		}
+		for i, pc := range p.conns {
+			if pc == c.pc {
+				p.conns = append(p.conns[:i], p.conns[i+1:]...)
+				pc.conn.Close()
+				break
+			}
+		}
+	})
+}
+
+// dialAMQP yapılandırmadaki TLS ayarları ve connection_name ile bağlanır. amqps://
+// adreslerinde RABBITMQ_CA_FILE ve istemci sertifikası (RABBITMQ_CERT_FILE/KEY_FILE) kullanılır.
+func dialAMQP(connectionName string) (*amqp.Connection, error) {
+	cfg := config.GetConfig()
+
+	tlsCfg, err := amqpTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := amqp.DialConfig(cfg.RabbitMQURL, amqp.Config{
+		Heartbeat:       10 * time.Second,
+		Locale:          "en_US",
+		TLSClientConfig: tlsCfg,
+		Properties: amqp.Table{
+			"connection_name": connectionName,
+			"product":         "tedalogger-logfetcher",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RabbitMQ dial error: %w", err)
+	}
+	return conn, nil
+}
+
+func amqpTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.RabbitMQCAFile == "" && cfg.RabbitMQCertFile == "" {
+		return nil, nil
+	}
+
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.RabbitMQCAFile != "" {
+		pem, err := os.ReadFile(cfg.RabbitMQCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("RabbitMQ CA read error: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("RabbitMQ CA file has no valid certificates: %s", cfg.RabbitMQCAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.RabbitMQCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.RabbitMQCertFile, cfg.RabbitMQKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("RabbitMQ client certificate error: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
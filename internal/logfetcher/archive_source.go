@@ -0,0 +1,200 @@
+// internal/logfetcher/archive_source.go
+
+package logfetcher
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"tedalogger-logfetcher/config"
+)
+
+// archiveSource .log, .gz ve .zst arşivlerini bir kez baştan sona okur. Geç eklenen
+// cihazların geçmiş syslog arşivlerini geri doldurmak (backfill) için kullanılır.
+type archiveSource struct {
+	paths   []string
+	nas     string
+	maxLine int
+
+	lines, acked, failed atomic.Int64
+}
+
+// backfillCloseTimeout Backfill sonunda sink'lerin boşalması için beklenen en uzun süredir.
+const backfillCloseTimeout = time.Minute
+
+// BackfillResult Backfill'in okuduğu ve işlediği satır sayılarıdır.
+type BackfillResult struct {
+	Lines  int64
+	Acked  int64 // indekslenen ya da URL içermediği için atlanan
+	Failed int64 // parse edilemeyen ya da ES'in kalıcı olarak reddettiği
+}
+
+// Backfill arşiv dosyalarını nasName NAS'ının logları olarak indeksler. brand boşsa NAS
+// API'den aranır. Geçici ES hataları başarılı olana kadar yerinde denenir.
+func Backfill(nasName, brand string, paths []string) (BackfillResult, error) {
+	if brand != "" {
+		addTemplateNAS([]NAS{{Nasname: nasName}})
+		updateNASRegistry([]NAS{{Nasname: nasName, Brand: brand}})
+	} else {
+		list, err := fetchNASList()
+		if err != nil {
+			return BackfillResult{}, fmt.Errorf("NAS list error: %w", err)
+		}
+		addTemplateNAS(list)
+		updateNASRegistry(list)
+		if _, ok := lookupNAS(nasName); !ok {
+			return BackfillResult{}, fmt.Errorf("NAS %s not found in NAS list", nasName)
+		}
+	}
+
+	ctx := context.Background()
+	router := getSinkRouter()
+	proc := newNASProcessor(router, "backfill", "[Backfill]", -1)
+	src := &archiveSource{
+		paths:   paths,
+		nas:     nasName,
+		maxLine: config.GetConfig().SyslogMaxMessage,
+	}
+	err := src.Run(ctx, func(m SourceMessage) { proc.process(ctx, m) })
+
+	// Sayaçlar zorunlu sink'lerin sonucunu bekledi; isteğe bağlı sink'lerde kalan belgeler
+	// süreç çıkmadan gönderilir.
+	closeCtx, cancel := context.WithTimeout(ctx, backfillCloseTimeout)
+	defer cancel()
+	if cerr := router.Close(closeCtx); cerr != nil {
+		log.Printf("[Backfill] Sink close error: %v", cerr)
+	}
+	return BackfillResult{
+		Lines:  src.lines.Load(),
+		Acked:  src.acked.Load(),
+		Failed: src.failed.Load(),
+	}, err
+}
+
+func (a *archiveSource) Name() string { return "archive" }
+
+func (a *archiveSource) Run(ctx context.Context, handle func(SourceMessage)) error {
+	for _, path := range a.paths {
+		if ctx.Err() != nil {
+			return nil
+		}
+		before := a.lines.Load()
+		if err := a.readFile(ctx, path, handle); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		log.Printf("[Backfill] %s done (%d lines)", path, a.lines.Load()-before)
+	}
+	return nil
+}
+
+func (a *archiveSource) readFile(ctx context.Context, path string, handle func(SourceMessage)) error {
+	// Kimlikler aynı dosyanın farklı yazılışlarında (göreli, ./) aynı kalsın.
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	r, err := openArchive(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Dosya bitmeden dönülmez; Backfill sayaçları tüm satırlar sonuçlandıktan sonra okunur.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	for lineNo := 1; ; lineNo++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Sınırı aşan satır kesilir; tek bir dev satır backfill'i durdurmaz.
+		raw, _, truncated, err := readBoundedLine(br, a.maxLine)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if truncated {
+			incCounter("backfill_truncated", 1)
+		}
+		line := strings.TrimRight(string(raw), "\r")
+		if line == "" {
+			if err == io.EOF {
+				return nil
+			}
+			continue
+		}
+		a.lines.Add(1)
+		wg.Add(1)
+		handle(SourceMessage{
+			Body: []byte(line),
+			Meta: LogMessage{FromHost: a.nas},
+			NAS:  a.nas,
+			ID:   fmt.Sprintf("archive:%s:%d", abs, lineNo),
+			Ack: func() {
+				a.acked.Add(1)
+				wg.Done()
+			},
+			Nack: func(err error, retryable bool) {
+				defer wg.Done()
+				a.failed.Add(1)
+				if !errors.Is(err, errUnparseable) {
+					log.Printf("[Backfill] ES index error, skipping line from %s: %v", path, err)
+				}
+			},
+		})
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// openArchive uzantıya göre gzip ya da zstd açar; diğer dosyalar düz metin okunur.
+func openArchive(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("gzip error: %w", err)
+		}
+		return &archiveReader{Reader: zr, closers: []io.Closer{zr, f}}, nil
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("zstd error: %w", err)
+		}
+		return &archiveReader{Reader: zr, closers: []io.Closer{zr.IOReadCloser(), f}}, nil
+	default:
+		return f, nil
+	}
+}
+
+type archiveReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *archiveReader) Close() error {
+	for _, c := range r.closers {
+		c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package logfetcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestArchiveReadFile sınırı aşan satırın kesilip okumanın sürdüğünü ve kimliklerin yolun
+// yazılışından bağımsız olduğunu sınar.
+func TestArchiveReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fw.log")
+	data := "first\n" + strings.Repeat("x", 100) + "\r\n\nlast"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(p string) []SourceMessage {
+		t.Helper()
+		var msgs []SourceMessage
+		a := &archiveSource{nas: "10.0.0.1", maxLine: 16}
+		err := a.readFile(context.Background(), p, func(m SourceMessage) {
+			msgs = append(msgs, m)
+			m.Ack()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msgs
+	}
+
+	msgs := read(path)
+	if len(msgs) != 3 {
+		t.Fatalf("read %d lines, want 3", len(msgs))
+	}
+	if got := string(msgs[1].Body); got != strings.Repeat("x", 16) {
+		t.Fatalf("long line = %q, want truncated to 16 bytes", got)
+	}
+	if got := string(msgs[2].Body); got != "last" {
+		t.Fatalf("last line = %q", got)
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range read("./fw.log") {
+		if m.ID != msgs[i].ID {
+			t.Fatalf("ID %q for ./fw.log, want %q", m.ID, msgs[i].ID)
+		}
+	}
+}
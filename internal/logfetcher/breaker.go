@@ -0,0 +1,143 @@
+// internal/logfetcher/breaker.go
+
+package logfetcher
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	// breakerHalfOpen bekleme bitti, tek bir deneme isteği sonuç bekliyor.
+	breakerHalfOpen
+)
+
+// circuitBreaker bir kümeye giden yazımları korur. Art arda ES_BREAKER_THRESHOLD istek
+// başarısız olursa (bağlantı, 5xx ya da 429 reddi) açılır; açıkken istek gönderilmez.
+// Bekleme bitince tek bir deneme isteğine izin verilir; başarılıysa kapanır, değilse
+// bekleme süresi ikiye katlanarak yeniden açılır.
+type circuitBreaker struct {
+	name        string
+	threshold   int
+	minCooldown time.Duration
+	maxCooldown time.Duration
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	changed   chan struct{}
+}
+
+func newCircuitBreaker(name string, cfg *config.Config) *circuitBreaker {
+	threshold := cfg.ESBreakerThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &circuitBreaker{
+		name:        name,
+		threshold:   threshold,
+		minCooldown: cfg.ESBreakerCooldown,
+		maxCooldown: cfg.ESBreakerMaxCooldown,
+		changed:     make(chan struct{}),
+	}
+}
+
+// allow istek gönderilebilirse true döner. Açık devrede bekleme bittiyse çağıran deneme
+// isteğini üstlenir ve sonucu success/failure ile bildirmek zorundadır.
+func (c *circuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if time.Now().Before(c.openUntil) {
+			return false
+		}
+		c.setState(breakerHalfOpen)
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *circuitBreaker) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures = 0
+	c.cooldown = 0
+	if c.state != breakerClosed {
+		log.Printf("[Breaker] %s closed, resuming writes", c.name)
+		c.setState(breakerClosed)
+	}
+}
+
+func (c *circuitBreaker) failure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures++
+	switch c.state {
+	case breakerHalfOpen:
+		c.cooldown *= 2
+		if c.cooldown > c.maxCooldown {
+			c.cooldown = c.maxCooldown
+		}
+	case breakerClosed:
+		if c.failures < c.threshold {
+			return
+		}
+		c.cooldown = c.minCooldown
+	default:
+		return
+	}
+	c.openUntil = time.Now().Add(c.cooldown)
+	incCounter("es_breaker_opened", 1)
+	log.Printf("[Breaker] %s open for %s after %d failed requests", c.name, c.cooldown, c.failures)
+	c.setState(breakerOpen)
+}
+
+// setState durum değiştiğinde changed kanalını kapatarak bekleyenleri uyandırır.
+func (c *circuitBreaker) setState(s breakerState) {
+	if c.state == s {
+		return
+	}
+	c.state = s
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// closed devre kapalıysa, yani küme yazımları kabul ediyorsa true döner.
+func (c *circuitBreaker) closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state == breakerClosed
+}
+
+// changes bir sonraki durum değişikliğinde kapanan kanalı döndürür.
+func (c *circuitBreaker) changes() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.changed
+}
+
+// openWait açık devrenin deneme isteğine kalan süresidir; kapalıysa 0.
+func (c *circuitBreaker) openWait() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != breakerOpen {
+		return 0
+	}
+	return time.Until(c.openUntil)
+}
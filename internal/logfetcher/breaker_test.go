@@ -0,0 +1,108 @@
+package logfetcher
+
+import (
+	"testing"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+func testBreaker() *circuitBreaker {
+	return newCircuitBreaker("test", &config.Config{
+		ESBreakerThreshold:   3,
+		ESBreakerCooldown:    time.Second,
+		ESBreakerMaxCooldown: 3 * time.Second,
+	})
+}
+
+// expire açık devrenin bekleme süresini bitmiş sayar.
+func expire(c *circuitBreaker) {
+	c.mu.Lock()
+	c.openUntil = time.Now().Add(-time.Millisecond)
+	c.mu.Unlock()
+}
+
+func TestCircuitBreakerOpensAtThreshold(t *testing.T) {
+	c := testBreaker()
+	changed := c.changes()
+
+	c.failure()
+	c.failure()
+	if !c.allow() || !c.closed() {
+		t.Fatal("breaker opened below threshold")
+	}
+	c.failure()
+	if c.allow() || c.closed() {
+		t.Fatal("breaker not open at threshold")
+	}
+	if w := c.openWait(); w <= 0 || w > time.Second {
+		t.Fatalf("openWait = %s, want within cooldown", w)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changes channel not closed on open")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	c := testBreaker()
+	c.failure()
+	c.failure()
+	c.success()
+	c.failure()
+	c.failure()
+	if !c.closed() {
+		t.Fatal("failures before a success counted toward the threshold")
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	c := testBreaker()
+	for i := 0; i < 3; i++ {
+		c.failure()
+	}
+
+	// Bekleme bitince yalnızca tek deneme isteğine izin verilir.
+	expire(c)
+	if !c.allow() {
+		t.Fatal("trial request not allowed after cooldown")
+	}
+	if c.allow() {
+		t.Fatal("second request allowed while half-open")
+	}
+
+	// Başarısız deneme bekleme süresini ikiye katlar, üst sınırı aşmaz.
+	for _, want := range []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		c.failure()
+		if c.allow() {
+			t.Fatal("breaker not reopened after failed trial")
+		}
+		if c.cooldown != want {
+			t.Fatalf("cooldown = %s, want %s", c.cooldown, want)
+		}
+		expire(c)
+		if !c.allow() {
+			t.Fatal("trial request not allowed after cooldown")
+		}
+	}
+
+	changed := c.changes()
+	c.success()
+	if !c.closed() || !c.allow() || c.openWait() != 0 {
+		t.Fatal("breaker not closed after successful trial")
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changes channel not closed on close")
+	}
+
+	// Kapandıktan sonra eşik ve bekleme süresi baştan sayılır.
+	for i := 0; i < 3; i++ {
+		c.failure()
+	}
+	if c.cooldown != time.Second {
+		t.Fatalf("cooldown = %s after reset, want 1s", c.cooldown)
+	}
+}
@@ -0,0 +1,476 @@
+// internal/logfetcher/bulk.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"tedalogger-logfetcher/config"
+)
+
+// bulkItem kuyruğa alınmış tek belgedir. done, belge yazıldığında nil ile ya da
+// esIndexError ile bulk isteğini gönderen worker goroutine'inden çağrılır.
+type bulkItem struct {
+	index string
+	op    string // boşsa "index"; data stream'ler ve kimlikli belgeler "create" kullanır
+	id    string // boşsa ES üretir
+	body  []byte
+	done  func(err error)
+
+	meta []byte // bulk eylem satırı; worker doldurur
+}
+
+// bulkIndexer bir kümenin (searchSink) _bulk yazıcısıdır. Her worker kendi tamponunu
+// ES_BULK_FLUSH_BYTES, ES_BULK_FLUSH_DOCS ya da ES_BULK_FLUSH_INTERVAL dolunca gönderir.
+// Kuyruk dolduğunda add bloklanır; böylece ES yavaşladığında kaynaklar da yavaşlar.
+type bulkIndexer struct {
+	// es Elasticsearch ya da OpenSearch kümesinin REST uçlarıdır.
+	es *esapi.API
+
+	flushBytes int
+	flushDocs  int
+	interval   time.Duration
+
+	queue   chan bulkItem
+	workers int
+
+	// inflight kuyruğa alınıp sonucu henüz bildirilmemiş belge sayısıdır; flushNow
+	// worker'lara tamponlarını hemen göndermelerini söyler.
+	inflight atomic.Int64
+	flushNow chan struct{}
+
+	// Geçici hatayla dönen belgeler worker içinde üstel beklemeyle yeniden denenir; worker
+	// beklerken kuyruk dolar ve kaynaklar yavaşlar.
+	retries  int
+	retryMin time.Duration
+	retryMax time.Duration
+
+	// breaker açıkken ES'e istek gönderilmez; AMQP tüketicileri de durdurulur.
+	breaker *circuitBreaker
+
+	// spool ayarlıysa geçici hatayla yazılamayan belgeler diske alınıp ack'lenir. Devre
+	// açıkken worker'lar ES'i beklemeden doğrudan spool'a yazar.
+	spool *spool
+}
+
+// errCircuitOpen devre açıkken gönderilmeyen belgelere verilir; geçici hata sayılır.
+var errCircuitOpen = &esIndexError{Status: 503, Msg: "circuit breaker open"}
+
+// newBulkIndexer name devre kesici kayıtlarında kümeyi belirtir.
+func newBulkIndexer(name string, es *esapi.API, cfg *config.Config) *bulkIndexer {
+	workers := cfg.ESBulkWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	b := &bulkIndexer{
+		es:         es,
+		flushBytes: cfg.ESBulkFlushBytes,
+		flushDocs:  cfg.ESBulkFlushDocs,
+		interval:   cfg.ESBulkFlushInterval,
+		queue:      make(chan bulkItem, cfg.ESBulkQueueSize),
+		retries:    cfg.ESBulkRetries,
+		retryMin:   cfg.ESBulkRetryMin,
+		retryMax:   cfg.ESBulkRetryMax,
+		breaker:    newCircuitBreaker(name, cfg),
+		workers:    workers,
+		flushNow:   make(chan struct{}),
+	}
+	go b.monitor()
+	if dir := spoolDir(name, cfg); dir != "" {
+		sp, err := openSpool(cfg, dir)
+		if err != nil {
+			log.Printf("[Spool] Disabled, open error: %v", err)
+		} else {
+			b.spool = sp
+			go b.drain()
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		go b.worker()
+	}
+	return b
+}
+
+// spoolDir SINK_TYPE kümesinin spool'u eskisi gibi doğrudan ES_SPOOL_DIR'dadır; diğer
+// sink'ler aynı segmentleri karıştırmasın diye kendi adlarıyla alt dizin kullanır.
+func spoolDir(name string, cfg *config.Config) string {
+	if cfg.ESSpoolDir == "" || strings.EqualFold(name, cfg.SinkType) {
+		return cfg.ESSpoolDir
+	}
+	return filepath.Join(cfg.ESSpoolDir, name)
+}
+
+func (b *bulkIndexer) add(item bulkItem) {
+	b.inflight.Add(1)
+	b.queue <- item
+}
+
+// flushAll worker tamponlarını beklemeden gönderir ve kuyruktaki tüm belgeler sonuçlanana
+// kadar bekler. Spool'a alınmış belgeler sonuçlanmış sayılır.
+func (b *bulkIndexer) flushAll(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for b.inflight.Load() > 0 {
+		for i := 0; i < b.workers; i++ {
+			select {
+			case b.flushNow <- struct{}{}:
+			default:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (b *bulkIndexer) worker() {
+	var items []bulkItem
+	var size int
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(items) == 0 {
+			return
+		}
+		b.flush(items)
+		items, size = nil, 0
+	}
+
+	for {
+		select {
+		case it := <-b.queue:
+			op := it.op
+			if op == "" {
+				op = "index"
+			}
+			action := map[string]any{"_index": it.index}
+			if it.id != "" {
+				action["_id"] = it.id
+			}
+			it.meta, _ = json.Marshal(map[string]any{op: action})
+			items = append(items, it)
+			size += len(it.meta) + len(it.body) + 2
+
+			if len(items) >= b.flushDocs || size >= b.flushBytes {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-b.flushNow:
+			flush()
+		}
+	}
+}
+
+type bulkResponse struct {
+	Errors bool                                `json:"errors"`
+	Items  []map[string]bulkResponseItemResult `json:"items"`
+}
+
+type bulkResponseItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// flush belgeleri gönderir ve her birinin sonucunu kendi done'ına iletir. Geçici hatayla
+// dönenler ES_BULK_RETRIES kez yeniden denenir; hâlâ yazılamayanlar spool varsa diske
+// yazılıp başarılı sayılır.
+func (b *bulkIndexer) flush(items []bulkItem) {
+	errs := make([]error, len(items))
+	pending := make([]int, len(items))
+	for i := range items {
+		pending[i] = i
+	}
+
+	if b.spool != nil && !b.breaker.closed() {
+		// ES'in düzelmesini beklemeden diske al; spool doluysa aşağıda normal yola düşülür.
+		if err := b.spool.append(items); err == nil {
+			pending = nil
+		}
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if !b.breaker.allow() {
+			for _, i := range pending {
+				errs[i] = errCircuitOpen
+			}
+			break
+		}
+
+		batch := make([]bulkItem, len(pending))
+		for j, i := range pending {
+			batch[j] = items[i]
+		}
+		res, requestFailed := b.send(batch)
+		b.record(res, requestFailed)
+
+		var retry []int
+		for j, i := range pending {
+			errs[i] = res[j]
+			if res[j] != nil && isRetryableIndexError(res[j]) {
+				retry = append(retry, i)
+			}
+		}
+		pending = retry
+		if len(pending) == 0 || attempt >= b.retries {
+			break
+		}
+		incCounter("es_bulk_retries", int64(len(pending)))
+		time.Sleep(expBackoff(attempt, b.retryMin, b.retryMax))
+	}
+
+	if b.spool != nil && len(pending) > 0 {
+		spooled := make([]bulkItem, len(pending))
+		for j, i := range pending {
+			spooled[j] = items[i]
+		}
+		if err := b.spool.append(spooled); err != nil {
+			log.Printf("[Spool] Write error (%d docs): %v", len(spooled), err)
+		} else {
+			for _, i := range pending {
+				errs[i] = nil
+			}
+		}
+	}
+
+	for i, it := range items {
+		it.done(errs[i])
+	}
+	b.inflight.Add(-int64(len(items)))
+}
+
+// record istek sonucunu devre kesiciye bildirir. 429 ile reddedilen belge varsa küme
+// doymuş sayılır.
+func (b *bulkIndexer) record(errs []error, requestFailed bool) {
+	if requestFailed {
+		b.breaker.failure()
+		return
+	}
+	for _, err := range errs {
+		var ie *esIndexError
+		if errors.As(err, &ie) && ie.Status == 429 {
+			b.breaker.failure()
+			return
+		}
+	}
+	b.breaker.success()
+}
+
+// send tek bir _bulk isteği yapar ve belge başına hatayı döndürür. İstek bütün olarak
+// başarısız olursa (bağlantı, 429, 5xx) tüm belgeler aynı hatayı alır ve requestFailed true
+// olur. Zaten var olan kimlikli belge (409) başarı sayılır; bu yalnızca ES_DETERMINISTIC_IDS
+// açıkken olur, kapalıyken tekrar gönderilen belge yeni bir kopya olarak yazılır.
+func (b *bulkIndexer) send(items []bulkItem) (errs []error, requestFailed bool) {
+	incCounter("es_bulk_requests", 1)
+	errs = make([]error, len(items))
+	failAll := func(err error) ([]error, bool) {
+		incCounter("es_index_errors", int64(len(items)))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs, true
+	}
+
+	var buf bytes.Buffer
+	for _, it := range items {
+		buf.Write(it.meta)
+		buf.WriteByte('\n')
+		buf.Write(it.body)
+		buf.WriteByte('\n')
+	}
+
+	res, err := b.es.Bulk(bytes.NewReader(buf.Bytes()), b.es.Bulk.WithContext(context.Background()))
+	if err != nil {
+		log.Printf("[Bulk] Request error (%d docs): %v", len(items), err)
+		return failAll(&esIndexError{Msg: err.Error()})
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.Printf("[Bulk] Response error (%d docs): %s", len(items), res.Status())
+		return failAll(&esIndexError{Status: res.StatusCode, Msg: res.String()})
+	}
+
+	var parsed bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return failAll(&esIndexError{Msg: fmt.Sprintf("bulk response parse error: %v", err)})
+	}
+	if len(parsed.Items) != len(items) {
+		return failAll(&esIndexError{Msg: fmt.Sprintf("bulk response has %d items, sent %d", len(parsed.Items), len(items))})
+	}
+
+	var indexed, duplicates, failed int64
+	for i, it := range parsed.Items {
+		for _, r := range it {
+			if r.Status >= 200 && r.Status < 300 {
+				indexed++
+				continue
+			}
+			if r.Status == 409 {
+				// Aynı kimlikli belge zaten yazılmış: yeniden teslim ya da tekrar oynatma.
+				duplicates++
+				continue
+			}
+			failed++
+			msg := "unknown error"
+			if r.Error != nil {
+				msg = r.Error.Type + ": " + r.Error.Reason
+			}
+			errs[i] = &esIndexError{Status: r.Status, Msg: msg}
+		}
+	}
+	incCounter("es_indexed", indexed)
+	if duplicates > 0 {
+		incCounter("es_duplicates", duplicates)
+	}
+	if failed > 0 {
+		incCounter("es_index_errors", failed)
+	}
+	return errs, false
+}
+
+// drain spool'daki segmentleri yazıldıkları sırayla ES'e gönderir. ES yazamadığı sürece
+// aynı batch'i backoff ile dener; kalıcı hatayla reddedilen belgeler atılır (kaynak çoktan
+// ack'lendiği için geri verilecek yer yoktur).
+func (b *bulkIndexer) drain() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if b.spool.pending() == 0 {
+			continue
+		}
+		if err := b.spool.seal(); err != nil {
+			log.Printf("[Spool] Seal error: %v", err)
+			continue
+		}
+		segments, err := b.spool.sealed()
+		if err != nil {
+			log.Printf("[Spool] List error: %v", err)
+			continue
+		}
+		for _, seg := range segments {
+			// Konum her batch'ten sonra kaydedilir; yeniden başlayınca yalnızca konumu
+			// kaydedilemeden kesilen son batch tekrar gönderilir.
+			done := b.spool.drainedTo(seg)
+			err := readSegment(seg, done, b.flushDocs, func(recs []spoolRecord, next int64) error {
+				b.drainBatch(recs)
+				done = next
+				return b.spool.setDrainedTo(seg, next)
+			})
+			if errors.Is(err, errSpoolCorrupt) {
+				incCounter("spool_corrupt", 1)
+				log.Printf("[Spool] Keeping unsent part of %s (from offset %d) as %s: %v", seg, done, spoolCorruptExt, err)
+				if err := b.spool.quarantine(seg, done); err != nil {
+					log.Printf("[Spool] Quarantine error %s: %v", seg, err)
+					break
+				}
+				continue
+			}
+			if err != nil {
+				log.Printf("[Spool] Read error %s: %v", seg, err)
+				break
+			}
+			if err := b.spool.remove(seg); err != nil {
+				log.Printf("[Spool] Remove error %s: %v", seg, err)
+				break
+			}
+			log.Printf("[Spool] Drained %s", seg)
+		}
+	}
+}
+
+func (b *bulkIndexer) drainBatch(recs []spoolRecord) {
+	items := make([]bulkItem, len(recs))
+	for i, r := range recs {
+		items[i] = bulkItem{meta: r.meta, body: r.body}
+	}
+
+	for attempt := 0; len(items) > 0; attempt++ {
+		if !b.breaker.allow() {
+			wait := b.breaker.openWait()
+			if wait <= 0 {
+				// Yarı açık: deneme isteğinin sonucu bekleniyor.
+				wait = b.interval
+			}
+			select {
+			case <-b.breaker.changes():
+			case <-time.After(wait):
+			}
+			continue
+		}
+		errs, requestFailed := b.send(items)
+		b.record(errs, requestFailed)
+		if requestFailed {
+			time.Sleep(expBackoff(attempt, b.retryMin, b.retryMax))
+			continue
+		}
+
+		var retry []bulkItem
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				incCounter("spool_drained", 1)
+			case isRetryableIndexError(err):
+				retry = append(retry, items[i])
+			default:
+				incCounter("spool_dropped", 1)
+				log.Printf("[Spool] Dropping document rejected by Elasticsearch: %v", err)
+			}
+		}
+		items = retry
+		if len(items) > 0 {
+			time.Sleep(expBackoff(attempt, b.retryMin, b.retryMax))
+		}
+	}
+}
+
+// monitor devre açıkken bekleme bitince ES'i yoklar. Trafik yokken de (AMQP durdurulmuş,
+// spool boş) devrenin kapanabilmesi için gereklidir.
+func (b *bulkIndexer) monitor() {
+	for {
+		select {
+		case <-b.breaker.changes():
+		case <-time.After(time.Second):
+		}
+		if b.breaker.closed() {
+			continue
+		}
+		if wait := b.breaker.openWait(); wait > 0 {
+			time.Sleep(wait)
+		}
+		if !b.breaker.allow() {
+			continue
+		}
+		res, err := b.es.Ping()
+		if err != nil {
+			b.breaker.failure()
+			continue
+		}
+		res.Body.Close()
+		if res.IsError() {
+			b.breaker.failure()
+		} else {
+			b.breaker.success()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// internal/logfetcher/charset.go
+
+package logfetcher
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	charsetUTF8        = "utf-8"
+	charsetWindows1254 = "windows-1254"
+	charsetISO88599    = "iso-8859-9"
+)
+
+type decodedBody struct {
+	Text         string
+	Charset      string
+	InvalidBytes int
+	// Geçersiz bayt bulunduğunda orijinal gövde bozulmadan saklanır.
+	OriginalB64 string
+}
+
+func forcedCharsetFor(nasName string) string {
+	return normalizeCharsetName(config.GetConfig().NASCharsets[nasName])
+}
+
+func normalizeCharsetName(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utf-8", "utf8":
+		return charsetUTF8
+	case "windows-1254", "cp1254", "win1254":
+		return charsetWindows1254
+	case "iso-8859-9", "iso8859-9", "latin5":
+		return charsetISO88599
+	default:
+		return ""
+	}
+}
+
+// decodeBody ham mesaj gövdesini UTF-8'e çevirir. Zorlanan bir karakter seti yoksa
+// gövde geçerli UTF-8 ise olduğu gibi kullanılır, değilse Türkçe tek baytlık
+// kodlamalardan biri tahmin edilir.
+func decodeBody(body []byte, forced string) decodedBody {
+	cs := forced
+	if cs == "" {
+		cs = detectCharset(body)
+	}
+
+	var d decodedBody
+	d.Charset = cs
+
+	switch cs {
+	case charsetWindows1254:
+		d.Text, d.InvalidBytes = decodeSingleByte(body, charmap.Windows1254)
+	case charsetISO88599:
+		d.Text, d.InvalidBytes = decodeSingleByte(body, charmap.ISO8859_9)
+	default:
+		d.Text, d.InvalidBytes = sanitizeUTF8(body)
+	}
+
+	if d.InvalidBytes > 0 {
+		d.OriginalB64 = base64.StdEncoding.EncodeToString(body)
+	}
+	return d
+}
+
+func detectCharset(body []byte) string {
+	if utf8.Valid(body) {
+		return charsetUTF8
+	}
+	// ISO-8859-9'da 0x80-0x9F aralığı kontrol karakterleridir; bu aralıkta bayt
+	// varsa gönderen büyük ihtimalle Windows-1254 kullanıyordur. İkisi 0xA0 üstünde
+	// aynı olduğundan, belirsiz durumda da Windows-1254 seçilir.
+	return charsetWindows1254
+}
+
+func decodeSingleByte(body []byte, cm *charmap.Charmap) (string, int) {
+	var sb strings.Builder
+	sb.Grow(len(body))
+
+	invalid := 0
+	for _, b := range body {
+		r := cm.DecodeByte(b)
+		if r == utf8.RuneError {
+			invalid++
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), invalid
+}
+
+func sanitizeUTF8(body []byte) (string, int) {
+	if utf8.Valid(body) {
+		return string(body), 0
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(body))
+
+	invalid := 0
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		}
+		sb.WriteRune(r)
+		body = body[size:]
+	}
+	return sb.String(), invalid
+}
@@ -2,98 +2,317 @@ package logfetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/streadway/amqp"
 	"tedalogger-logfetcher/config"
 )
 
-// dateString fonksiyonu, gün/ay/yıl şeklinde bir string döndürür
-func dateString() string {
-	return time.Now().Format("02-01-2006")
+const requeueDelay = 2 * time.Second
+
+// amqpSource tek bir kuyruğu bir AMQP oturumu boyunca tüketir.
+type amqpSource struct {
+	queueName string
+	es        *esapi.API
+	onReady   func()
+
+	// dl nil ise retry/DLQ topolojisi kapalıdır; hatalı mesajlar nack ile geri verilir.
+	dl *deadLetterer
+	// stream nil değilse kuyruk stream'dir: yeniden teslim olmadığından geçici hatalar
+	// yerinde tekrar denenir ve konum offset kaydıyla takip edilir.
+	stream *streamTracker
+
+	// health nil değilse zorunlu sink'lerden biri yazım kabul etmezken tüketim durdurulur:
+	// klasik kuyruklarda tüketici iptal edilip sink'ler düzelince yeniden başlatılır;
+	// stream'lerde teslimatlar okunmaz ve prefetch dolunca broker göndermeyi keser (yeniden
+	// tüketim offset'i karıştırırdı).
+	health healthNotifier
 }
 
-func startConsumer(ctx context.Context, queueName, brand, nasIP string) error {
-	amqpURL := config.GetConfig().RabbitMQURL
-	conn, err := amqp.Dial(amqpURL)
-	if err != nil {
-		return fmt.Errorf("RabbitMQ dial error: %w", err)
+// startConsumer tek bir AMQP oturumu boyunca kuyruğu tüketir. Context iptal edilirse nil,
+// bağlantı/kanal kapanırsa hata döner; yeniden bağlanma superviseConsumer'ın işidir.
+func startConsumer(ctx context.Context, queueName, brand, nasIP string, onReady func()) error {
+	router := getSinkRouter()
+	p := newLogPipeline(router, "queue="+queueName, brand, nasIP)
+	proc := &sourceProcessor{
+		kind:    "amqp",
+		resolve: func(string) *logPipeline { return p },
 	}
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return fmt.Errorf("Channel error: %w", err)
-	}
-
-	msgs, err := ch.Consume(
-		queueName,
-		"",
-		true,  // autoAck
-		false, // exclusive
-		false, // noLocal
-		false, // noWait
-		nil,
-	)
+	if isStreamQueue(queueName) {
+		proc.retries = -1
+	}
+
+	src := &amqpSource{queueName: queueName, es: router.offsetAPI(), onReady: onReady}
+	if config.GetConfig().RabbitMQPauseOnBackpressure {
+		src.health = router
+	}
+	return src.Run(ctx, func(m SourceMessage) { proc.process(ctx, m) })
+}
+
+func (s *amqpSource) Name() string { return "queue=" + s.queueName }
+
+func (s *amqpSource) Run(ctx context.Context, handle func(SourceMessage)) error {
+	cfg := config.GetConfig()
+	pch, err := getAMQPPool().acquire()
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return fmt.Errorf("Consume error: %w", err)
+		return err
+	}
+	defer pch.release()
+
+	// Bağlantı koptuğunda üzerindeki kanallar da aynı hatayla kapanır.
+	ch := pch.ch
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	if err := ch.Qos(cfg.RabbitMQPrefetch, 0, false); err != nil {
+		return fmt.Errorf("Qos error: %w", err)
+	}
+
+	if cfg.RabbitMQDeadLetter {
+		s.dl, err = declareDeadLetterTopology(ch, s.queueName)
+		if err != nil {
+			return fmt.Errorf("Dead-letter topology error: %w", err)
+		}
 	}
 
-	esClient, err := connectES()
+	consumerTag := ""
+	var consumeArgs amqp.Table
+	if isStreamQueue(s.queueName) {
+		store, err := newStreamOffsetStore(cfg, s.es, s.queueName)
+		if err != nil {
+			return err
+		}
+		s.stream = newStreamTracker(store)
+		defer s.stream.close()
+		start, err := s.stream.startArg()
+		if err != nil {
+			return fmt.Errorf("Stream offset error: %w", err)
+		}
+		consumeArgs = amqp.Table{"x-stream-offset": start}
+		consumerTag = cfg.RabbitMQStreamConsumerName
+		log.Printf("[Stream] Consuming %s from %v", s.queueName, start)
+	} else {
+		// Durdurup yeniden başlatabilmek için tüketici etiketini biz veriyoruz.
+		consumerTag = fmt.Sprintf("%s-%s-%d", cfg.RabbitMQConnectionName, s.queueName, time.Now().UnixNano())
+	}
+
+	// Mesajlar yalnızca zorunlu sink'ler yazmayı onayladıktan sonra ack'lenir; işlem
+	// sırasında kanal kapanırsa ack'lenmemiş mesajlar RabbitMQ tarafından yeniden teslim edilir.
+	consume := func() (<-chan amqp.Delivery, error) {
+		msgs, err := ch.Consume(
+			s.queueName,
+			consumerTag,
+			false, // autoAck
+			false, // exclusive
+			false, // noLocal
+			false, // noWait
+			consumeArgs,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Consume error: %w", err)
+		}
+		return msgs, nil
+	}
+	msgs, err := consume()
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return fmt.Errorf("Elasticsearch connection error: %w", err)
+		return err
 	}
 
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
+	if s.onReady != nil {
+		s.onReady()
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case d, ok := <-msgs:
-				if !ok {
-					return
-				}
+	commitTicker := time.NewTicker(streamCommitInterval)
+	defer commitTicker.Stop()
 
-				rawMsg := string(d.Body)
-				lm := LogMessage{
-					Message: rawMsg,
+	paused := false
+	for {
+		deliveries := msgs
+		var healthChanged <-chan struct{}
+		if s.health != nil {
+			healthChanged = s.health.healthChanges()
+			saturated := !s.health.Healthy()
+			switch {
+			case saturated && !paused:
+				paused = true
+				incCounter("amqp_paused", 1)
+				log.Printf("[Backpressure] Sink saturated, pausing consumer (queue=%s)", s.queueName)
+				if s.stream == nil {
+					// İptalden önce gelmiş teslimatlar msgs kapanana kadar işlenir.
+					if err := ch.Cancel(consumerTag, false); err != nil {
+						return fmt.Errorf("Cancel error: %w", err)
+					}
 				}
-				doc := parseAndDetermineBrand(lm)
-				doc.NASName = nasIP
+			case !saturated && paused && (s.stream != nil || msgs == nil):
+				paused = false
+				log.Printf("[Backpressure] Sinks healthy, resuming consumer (queue=%s)", s.queueName)
+				if s.stream == nil {
+					if msgs, err = consume(); err != nil {
+						return err
+					}
+					deliveries = msgs
+				}
+			}
+			if paused && s.stream != nil {
+				deliveries = nil
+			}
+		}
 
-				if doc.URL == "" {
-					continue
+		select {
+		case <-healthChanged:
+		case <-ctx.Done():
+			if s.stream != nil {
+				if err := s.stream.commit(); err != nil {
+					log.Printf("[Stream] Final offset commit error (queue=%s): %v", s.queueName, err)
+				}
+			}
+			return nil
+		case amqpErr := <-chClosed:
+			return fmt.Errorf("channel closed: %v", amqpErr)
+		case <-commitTicker.C:
+			if s.stream != nil {
+				if err := s.stream.commit(); err != nil {
+					return fmt.Errorf("stream offset commit: %w", err)
 				}
-				if doc.Brand != "forti" && doc.Brand != "ruijie" {
+			}
+		case d, ok := <-deliveries:
+			if !ok {
+				if paused {
+					// Tüketici iptali tamamlandı.
+					msgs = nil
 					continue
 				}
+				return fmt.Errorf("delivery channel closed")
+			}
+			finished := func() {}
+			if s.stream != nil {
+				finished = s.stream.begin(d)
+			}
+			for _, m := range s.messages(ctx, d, finished) {
+				handle(m)
+			}
 
-				indexName := fmt.Sprintf("%s-%s",
-					strings.ReplaceAll(nasIP, ".", "_"),
-					dateString(), // Burada dateString() fonksiyonunu çağırıyoruz
-				)
-
-				if err := indexLogToES(esClient, doc, indexName); err != nil {
-					log.Printf("ES index error (queue=%s): %v", queueName, err)
-				} else {
-					log.Printf("Indexed (queue=%s) brand=%s, url=%s, index=%s",
-						queueName, doc.Brand, doc.URL, indexName)
+			if s.stream != nil && s.stream.shouldCommit() {
+				if err := s.stream.commit(); err != nil {
+					return fmt.Errorf("stream offset commit: %w", err)
 				}
 			}
 		}
-	}()
+	}
+}
+
+// messages teslimatı gerekirse açar ve kayıtlara böler. Teslimat, içindeki tüm kayıtlar
+// sonuçlandığında bir kez ack'lenir ya da reddedilir. Birden çok kayıtlı gövdede parse
+// edilemeyen tek satır teslimatı DLQ'ya göndermez; yalnızca sayılır. finished teslimat
+// ack'lendiğinde ya da reddedildiğinde çağrılır.
+func (s *amqpSource) messages(ctx context.Context, d amqp.Delivery, finished func()) []SourceMessage {
+	cfg := config.GetConfig()
+	body, err := decodeContentEncoding(d.Body, d.ContentEncoding, cfg.RabbitMQMaxBodySize)
+	if err != nil {
+		incCounter("amqp_decode_errors", 1)
+		log.Printf("Body decode error (queue=%s): %v", s.queueName, err)
+		s.reject(ctx, d, err.Error(), false)
+		finished()
+		return nil
+	}
+
+	records := splitBody(body, cfg.RabbitMQBodyFraming)
+	if len(records) == 0 {
+		d.Ack(false)
+		finished()
+		return nil
+	}
+	if len(records) > 1 {
+		incCounter("amqp_batched_deliveries", 1)
+		incCounter("amqp_batched_records", int64(len(records)))
+	}
+
+	batch := newBatchAck(len(records), func(err error, retryable bool) {
+		defer finished()
+		if err == nil {
+			d.Ack(false)
+			return
+		}
+		if !errors.Is(err, errUnparseable) {
+			log.Printf("ES index error (queue=%s): %v", s.queueName, err)
+		}
+		s.reject(ctx, d, err.Error(), retryable)
+	})
+
+	// Kaydın konumu: stream'de offset, klasik kuyrukta yayıncının verdiği message-id.
+	// Retry kuyruğundan dönen mesaj message-id'sini korur.
+	var origin string
+	if off, ok := d.Headers["x-stream-offset"].(int64); ok {
+		origin = fmt.Sprintf("stream:%s:%d", s.queueName, off)
+	} else if d.MessageId != "" {
+		origin = fmt.Sprintf("amqp:%s:%s", s.queueName, d.MessageId)
+	}
 
-	<-done
-	ch.Close()
-	conn.Close()
-	return nil
+	out := make([]SourceMessage, len(records))
+	for i, rec := range records {
+		var id string
+		if origin != "" {
+			id = fmt.Sprintf("%s:%d", origin, i)
+		}
+		out[i] = SourceMessage{
+			Body: rec,
+			ID:   id,
+			// Yeniden teslimde kardeş kayıtlar 409 ile elensin (bkz. batchAck).
+			RequireID: id != "" && len(records) > 1,
+			Ack:       batch.ack,
+			Nack: func(err error, retryable bool) {
+				if len(records) > 1 && errors.Is(err, errUnparseable) {
+					batch.ack()
+					return
+				}
+				batch.nack(err, retryable)
+			},
+		}
+	}
+	return out
+}
+
+// reject başarısız mesajı retryable ise gecikmeli yeniden denemeye, değilse DLQ'ya
+// gönderir. Topoloji kapalıysa ya da yayın başarısız olursa nack'e düşülür. Yeniden
+// teslim edilen mesaj baştan işlenir; ES_DETERMINISTIC_IDS kapalıysa önceki denemede
+// yazılmış belgeler tekrar yazılır.
+func (s *amqpSource) reject(ctx context.Context, d amqp.Delivery, reason string, retryable bool) {
+	if s.dl != nil {
+		var err error
+		if retryable {
+			err = s.dl.retry(d, reason)
+		} else {
+			err = s.dl.deadLetter(d, reason)
+		}
+		if err == nil {
+			d.Ack(false)
+			return
+		}
+		log.Printf("Dead-letter publish error (queue=%s): %v", s.queueName, err)
+		retryable = true
+	}
+
+	if s.stream != nil {
+		// Stream kuyruklarında nack anlamsızdır; ack yalnızca akış kontrolü içindir.
+		incCounter("messages_rejected", 1)
+		d.Ack(false)
+		return
+	}
+
+	if !retryable {
+		incCounter("messages_rejected", 1)
+		d.Nack(false, false)
+		return
+	}
+
+	incCounter("messages_requeued", 1)
+	// ES erişilemezken aynı mesajın anında geri gelip döngüye girmesini önler.
+	select {
+	case <-ctx.Done():
+	case <-time.After(requeueDelay):
+	}
+	d.Nack(false, true)
 }
@@ -0,0 +1,231 @@
+// internal/logfetcher/datastream.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	indexModeDaily      = "daily"
+	indexModeDataStream = "datastream"
+
+	// aliasPrefix NAS ve kiracı alias'larının önekidir (urlfilter-<nas>, urlfilter-tenant-<kiracı>).
+	aliasPrefix = "urlfilter-"
+
+	dataStreamPipelineID = "tedalogger-legacy-to-datastream"
+)
+
+func dataStreamMode() bool {
+	return strings.EqualFold(config.GetConfig().ESIndexMode, indexModeDataStream)
+}
+
+// dataStreamName NAS'ın data stream adıdır. ES adlandırma kuralına (logs-<dataset>-<namespace>)
+// uymak için namespace küçük harfe çevrilir ve harf, rakam, _ dışındaki karakterler (tire
+// dahil) _ yapılır; böylece adlar sözlük sırasıyla sıralanır ve tireli NAS adları bozmaz.
+func dataStreamName(nasName string) string {
+	return config.GetConfig().ESDataStreamPrefix + streamNamespace(nasName)
+}
+
+func streamNamespace(nasName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(nasName) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// legacyStreamDoc legacy belgeye data stream'lerin zorunlu tuttuğu @timestamp alanını ekler.
+type legacyStreamDoc struct {
+	StreamTime time.Time `json:"@timestamp"`
+	ParsedLog
+}
+
+// Kurulumu başarısız olan stream'ler bu aralıkta üstel artan sürelerle yeniden denenir.
+const (
+	dataStreamRetryMin = 5 * time.Second
+	dataStreamRetryMax = 5 * time.Minute
+)
+
+// streamSetup bir data stream'in sink'in kümesindeki kurulum durumudur.
+type streamSetup struct {
+	ready atomic.Bool
+
+	mu       sync.Mutex
+	failures int
+	retryAt  time.Time
+}
+
+// ensureDataStream data stream'i ve alias'larını sink'in kümesine ilk yazımdan önce kurar.
+// Başarısız olursa belge başına değil, geri çekilme süresi dolunca yeniden denenir; o sırada
+// başka bir belge kurulumu yürütüyorsa beklenmez. Yazım her durumda yapılır, küme stream'i
+// şablondan açar; yalnızca alias'lar eksik kalır.
+func (s *searchSink) ensureDataStream(stream, nasName string) {
+	v, _ := s.streams.LoadOrStore(stream, &streamSetup{})
+	st := v.(*streamSetup)
+	if st.ready.Load() || !st.mu.TryLock() {
+		return
+	}
+	defer st.mu.Unlock()
+	if st.ready.Load() || time.Now().Before(st.retryAt) {
+		return
+	}
+
+	// OpenSearch data stream'lere alias bağlanamaz; NAS/kiracı alias'ları yalnızca ES'te var.
+	if err := createDataStream(s.bulk.es, stream, nasName, s.name == sinkElasticsearch); err != nil {
+		delay := expBackoff(st.failures, dataStreamRetryMin, dataStreamRetryMax)
+		st.failures++
+		st.retryAt = time.Now().Add(delay)
+		incCounter("datastream_setup_errors", 1)
+		log.Printf("[DataStream] Setup error for %s (%s), retrying in %s: %v", stream, s.name, delay.Round(time.Second), err)
+		return
+	}
+	st.ready.Store(true)
+}
+
+func createDataStream(es *esapi.API, stream, nasName string, aliases bool) error {
+	res, err := es.Indices.CreateDataStream(stream)
+	if err != nil {
+		return fmt.Errorf("create data stream: %w", err)
+	}
+	data, err := readBody(res)
+	if err != nil {
+		return fmt.Errorf("create data stream: %w", err)
+	}
+	if res.IsError() && !bytes.Contains(data, []byte("resource_already_exists_exception")) {
+		return fmt.Errorf("create data stream: %s %s", res.Status(), data)
+	}
+	if !aliases {
+		return nil
+	}
+
+	actions := []map[string]any{
+		{"add": map[string]any{"index": stream, "alias": aliasPrefix + streamNamespace(nasName)}},
+	}
+	if tenant := config.GetConfig().ESNASTenants[nasName]; tenant != "" {
+		actions = append(actions, map[string]any{
+			"add": map[string]any{"index": stream, "alias": aliasPrefix + "tenant-" + streamNamespace(tenant)},
+		})
+	}
+	body, _ := json.Marshal(map[string]any{"actions": actions})
+	res, err = es.Indices.UpdateAliases(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("update aliases: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("update aliases: %s", res.String())
+	}
+	return nil
+}
+
+func dataStreamPipelineBody() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"description": "tedalogger legacy daily index -> data stream",
+		"processors": []map[string]any{
+			{"set": map[string]any{
+				"field":              "@timestamp",
+				"copy_from":          "timestamp",
+				"override":           false,
+				"ignore_empty_value": true,
+			}},
+			// Zamanı hiç okunamamış eski belgeler data stream'e @timestamp olmadan giremez.
+			{"set": map[string]any{
+				"field":    "@timestamp",
+				"value":    "{{{_ingest.timestamp}}}",
+				"override": false,
+			}},
+		},
+	})
+}
+
+// MigrateIndicesToDataStream desene uyan günlük indeksleri NAS'larının data stream'ine
+// kopyalar. ECS şemasındaki stream'ler için legacy->ECS pipeline'ı kullanılır. deleteSource
+// verilirse eski indeks, kopya doğrulandıktan sonra silinir ve adı stream'e alias olarak
+// bağlanır; hâlâ yazılabilen günler atlanır (bkz. migrateIndex).
+func MigrateIndicesToDataStream(pattern string, deleteSource bool) error {
+	es, err := connectES()
+	if err != nil {
+		return err
+	}
+	if err := InstallTemplates(es); err != nil {
+		return err
+	}
+	if err := installECSPipeline(es); err != nil {
+		return err
+	}
+	body, err := dataStreamPipelineBody()
+	if err != nil {
+		return err
+	}
+	res, err := es.Ingest.PutPipeline(dataStreamPipelineID, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("put pipeline error: %w", err)
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("put pipeline response error: %s", res.String())
+	}
+
+	indices, err := listIndices(es, pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(indices)
+
+	for _, src := range indices {
+		nas, ok := nasFromDailyIndex(src)
+		if !ok {
+			log.Printf("[DataStream] %s is not a daily index, skipping", src)
+			continue
+		}
+		stream := dataStreamName(nas)
+		if err := createDataStream(es.API, stream, nas, true); err != nil {
+			return fmt.Errorf("%s: %w", stream, err)
+		}
+
+		pipeline := dataStreamPipelineID
+		if schemaForIndex(stream) == schemaECS {
+			pipeline = ecsPipelineID
+		}
+		if err := migrateIndex(es, src, stream, pipeline, "create", deleteSource); err != nil {
+			return fmt.Errorf("%s: %w", stream, err)
+		}
+	}
+	return nil
+}
+
+// nasFromDailyIndex "<nas>-GG-AA-YYYY" adından NAS kısmını çıkarır. Legacy adlarda noktalar
+// _ yapıldığı için dönen değer streamNamespace'ten geçince aynı kalır.
+func nasFromDailyIndex(index string) (string, bool) {
+	nas, _, ok := splitDailyIndex(index, time.UTC)
+	return nas, ok
+}
+
+// splitDailyIndex günlük indeks adını NAS kısmına ve gününün loc'taki başlangıcına ayırır.
+func splitDailyIndex(index string, loc *time.Location) (string, time.Time, bool) {
+	if len(index) < len("x-02-01-2006") {
+		return "", time.Time{}, false
+	}
+	cut := len(index) - len("-02-01-2006")
+	day, err := time.ParseInLocation("-02-01-2006", index[cut:], loc)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return index[:cut], day, true
+}
@@ -0,0 +1,65 @@
+package logfetcher
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func TestNASFromDailyIndex(t *testing.T) {
+	tests := []struct {
+		index string
+		nas   string
+		ok    bool
+	}{
+		{"10_0_0_1-19-10-2026", "10_0_0_1", true},
+		{"fw-ankara-01-19-10-2026", "fw-ankara-01", true},
+		{"x-01-01-2026", "x", true},
+		{"10_0_0_1-31-02-2026", "", false},
+		{"10_0_0_1-2026-10-19", "", false},
+		{"10_0_0_1-19-10-2026-ecs", "", false},
+		{"-19-10-2026", "", false},
+		{"tedalogger-quarantine", "", false},
+	}
+	for _, tt := range tests {
+		nas, ok := nasFromDailyIndex(tt.index)
+		if nas != tt.nas || ok != tt.ok {
+			t.Errorf("nasFromDailyIndex(%q) = %q, %v; want %q, %v", tt.index, nas, ok, tt.nas, tt.ok)
+		}
+	}
+}
+
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) Perform(r *http.Request) (*http.Response, error) { return f(r) }
+
+// TestEnsureDataStreamBackoff kurulamayan stream'in her belgede yeniden denenmediğini sınar.
+func TestEnsureDataStreamBackoff(t *testing.T) {
+	var calls int
+	status := http.StatusInternalServerError
+	api := esapi.New(transportFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(`{}`)), Header: http.Header{}}, nil
+	}))
+	s := &searchSink{name: sinkOpenSearch, bulk: &bulkIndexer{es: api}}
+
+	for i := 0; i < 100; i++ {
+		s.ensureDataStream("logs-tedalogger-fw1", "fw1")
+	}
+	if calls != 1 {
+		t.Fatalf("create called %d times during backoff, want 1", calls)
+	}
+
+	v, _ := s.streams.Load("logs-tedalogger-fw1")
+	v.(*streamSetup).retryAt = time.Time{}
+	status = http.StatusOK
+	s.ensureDataStream("logs-tedalogger-fw1", "fw1")
+	s.ensureDataStream("logs-tedalogger-fw1", "fw1")
+	if calls != 2 || !v.(*streamSetup).ready.Load() {
+		t.Fatalf("calls = %d, ready = %v after retry", calls, v.(*streamSetup).ready.Load())
+	}
+}
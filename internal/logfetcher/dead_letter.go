@@ -0,0 +1,164 @@
+// internal/logfetcher/dead_letter.go
+
+package logfetcher
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	headerRetryCount    = "x-retry-count"
+	headerFailureReason = "x-failure-reason"
+	headerFailedAt      = "x-failed-at"
+	headerOriginalQueue = "x-original-queue"
+)
+
+func retryQueueName(queue string) string { return queue + ".retry" }
+func deadQueueName(queue string) string  { return queue + ".dlq" }
+
+// deadLetterer bir NAS kuyruğu için retry ve dead-letter yayınlarını yapar.
+// Retry kuyruğunda TTL dolan mesajlar RabbitMQ tarafından x-death başlığı eklenerek
+// ana kuyruğa geri gönderilir.
+type deadLetterer struct {
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+	// mu yayın ile onayını birlikte sıralar. Ack/Nack bulk worker'larından eşzamanlı gelir;
+	// confirms kanalı yayın sırasıyla dolduğundan kilitsiz okuyan bir goroutine başka bir
+	// yayının onayını alabilirdi.
+	mu sync.Mutex
+
+	queue         string
+	retryExchange string
+	dlx           string
+	maxRetries    int
+}
+
+// declareDeadLetterTopology retry/dlq exchange ve kuyruklarını tanımlar ve kanalı
+// publisher confirm moduna alır. Topoloji idempotent olarak her başlangıçta tanımlanır.
+func declareDeadLetterTopology(ch *amqp.Channel, queue string) (*deadLetterer, error) {
+	cfg := config.GetConfig()
+
+	for _, ex := range []string{cfg.RabbitMQRetryExchange, cfg.RabbitMQDeadLetterExchange} {
+		if err := ch.ExchangeDeclare(ex, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+			return nil, fmt.Errorf("exchange declare %s: %w", ex, err)
+		}
+	}
+
+	retryQ := retryQueueName(queue)
+	_, err := ch.QueueDeclare(retryQ, true, false, false, false, amqp.Table{
+		"x-message-ttl":             int64(cfg.RabbitMQRetryDelay / time.Millisecond),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("queue declare %s: %w", retryQ, err)
+	}
+	if err := ch.QueueBind(retryQ, queue, cfg.RabbitMQRetryExchange, false, nil); err != nil {
+		return nil, fmt.Errorf("queue bind %s: %w", retryQ, err)
+	}
+
+	deadQ := deadQueueName(queue)
+	if _, err := ch.QueueDeclare(deadQ, true, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("queue declare %s: %w", deadQ, err)
+	}
+	if err := ch.QueueBind(deadQ, queue, cfg.RabbitMQDeadLetterExchange, false, nil); err != nil {
+		return nil, fmt.Errorf("queue bind %s: %w", deadQ, err)
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("confirm mode: %w", err)
+	}
+
+	return &deadLetterer{
+		ch:            ch,
+		confirms:      ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		queue:         queue,
+		retryExchange: cfg.RabbitMQRetryExchange,
+		dlx:           cfg.RabbitMQDeadLetterExchange,
+		maxRetries:    cfg.RabbitMQMaxRetries,
+	}, nil
+}
+
+// retry mesajı gecikmeli olarak yeniden denenmek üzere retry kuyruğuna gönderir; deneme
+// sayısı aşıldıysa dead-letter kuyruğuna düşürür. Çağıran, nil dönerse orijinali ack'lemelidir.
+func (dl *deadLetterer) retry(d amqp.Delivery, reason string) error {
+	count := headerInt(d.Headers, headerRetryCount)
+	if count >= dl.maxRetries {
+		return dl.deadLetter(d, fmt.Sprintf("max retries (%d) exceeded: %s", dl.maxRetries, reason))
+	}
+
+	headers := copyHeaders(d.Headers)
+	headers[headerRetryCount] = int32(count + 1)
+	headers[headerFailureReason] = reason
+
+	incCounter("messages_retried", 1)
+	return dl.publish(dl.retryExchange, d, headers)
+}
+
+// deadLetter mesajı hata nedeni ve mevcut x-death geçmişiyle birlikte DLQ'ya gönderir.
+func (dl *deadLetterer) deadLetter(d amqp.Delivery, reason string) error {
+	headers := copyHeaders(d.Headers)
+	headers[headerFailureReason] = reason
+	headers[headerFailedAt] = time.Now().UTC().Format(time.RFC3339)
+	headers[headerOriginalQueue] = dl.queue
+
+	incCounter("messages_dead_lettered", 1)
+	return dl.publish(dl.dlx, d, headers)
+}
+
+func (dl *deadLetterer) publish(exchange string, d amqp.Delivery, headers amqp.Table) error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	err := dl.ch.Publish(exchange, dl.queue, false, false, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		Timestamp:       d.Timestamp,
+		MessageId:       d.MessageId,
+		Body:            d.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("publish to %s: %w", exchange, err)
+	}
+
+	conf, ok := <-dl.confirms
+	if !ok {
+		return fmt.Errorf("publish to %s: channel closed before confirm", exchange)
+	}
+	if !conf.Ack {
+		return fmt.Errorf("publish to %s: broker nacked", exchange)
+	}
+	return nil
+}
+
+func copyHeaders(in amqp.Table) amqp.Table {
+	out := make(amqp.Table, len(in)+3)
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func headerInt(h amqp.Table, key string) int {
+	switch v := h[key].(type) {
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,138 @@
+// internal/logfetcher/dead_letter_admin.go
+
+package logfetcher
+
+import (
+	"fmt"
+
+	"github.com/streadway/amqp"
+	"tedalogger-logfetcher/config"
+)
+
+type DeadLetter struct {
+	Reason     string
+	FailedAt   string
+	RetryCount int
+	Deaths     []amqp.Table
+	Body       string
+}
+
+// InspectDeadLetters NAS kuyruğunun DLQ'sundaki en fazla limit mesajı okur ve
+// hepsini kuyruğa geri bırakır; kuyruğun içeriği değişmez.
+func InspectDeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	conn, ch, err := adminChannel()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer ch.Close()
+
+	var (
+		out  []DeadLetter
+		last uint64
+	)
+	for limit <= 0 || len(out) < limit {
+		d, ok, err := ch.Get(deadQueueName(queue), false)
+		if err != nil {
+			return nil, fmt.Errorf("get error: %w", err)
+		}
+		if !ok {
+			break
+		}
+		last = d.DeliveryTag
+		out = append(out, deadLetterFromDelivery(d))
+	}
+
+	if last > 0 {
+		if err := ch.Nack(last, true, true); err != nil {
+			return nil, fmt.Errorf("requeue error: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// ReplayDeadLetters DLQ'daki en fazla limit mesajı ana kuyruğa geri yayınlar. Yayın
+// broker tarafından onaylanmadan DLQ'daki kopya silinmez.
+func ReplayDeadLetters(queue string, limit int) (int, error) {
+	conn, ch, err := adminChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	defer ch.Close()
+
+	if err := ch.Confirm(false); err != nil {
+		return 0, fmt.Errorf("confirm mode: %w", err)
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+
+	replayed := 0
+	for limit <= 0 || replayed < limit {
+		d, ok, err := ch.Get(deadQueueName(queue), false)
+		if err != nil {
+			return replayed, fmt.Errorf("get error: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		headers := copyHeaders(d.Headers)
+		delete(headers, headerRetryCount)
+		delete(headers, headerFailureReason)
+		delete(headers, headerFailedAt)
+		delete(headers, headerOriginalQueue)
+
+		err = ch.Publish("", queue, false, false, amqp.Publishing{
+			Headers:         headers,
+			ContentType:     d.ContentType,
+			ContentEncoding: d.ContentEncoding,
+			DeliveryMode:    amqp.Persistent,
+			Timestamp:       d.Timestamp,
+			MessageId:       d.MessageId,
+			Body:            d.Body,
+		})
+		if err != nil {
+			d.Nack(false, true)
+			return replayed, fmt.Errorf("publish error: %w", err)
+		}
+		if conf := <-confirms; !conf.Ack {
+			d.Nack(false, true)
+			return replayed, fmt.Errorf("broker nacked replay of message %d", replayed+1)
+		}
+		if err := d.Ack(false); err != nil {
+			return replayed, fmt.Errorf("ack error: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func adminChannel() (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := dialAMQP(config.GetConfig().RabbitMQConnectionName + "-admin")
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("Channel error: %w", err)
+	}
+	return conn, ch, nil
+}
+
+func deadLetterFromDelivery(d amqp.Delivery) DeadLetter {
+	dl := DeadLetter{
+		RetryCount: headerInt(d.Headers, headerRetryCount),
+		Body:       string(d.Body),
+	}
+	dl.Reason, _ = d.Headers[headerFailureReason].(string)
+	dl.FailedAt, _ = d.Headers[headerFailedAt].(string)
+	if deaths, ok := d.Headers["x-death"].([]interface{}); ok {
+		for _, x := range deaths {
+			if t, ok := x.(amqp.Table); ok {
+				dl.Deaths = append(dl.Deaths, t)
+			}
+		}
+	}
+	return dl
+}
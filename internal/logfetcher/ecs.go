@@ -0,0 +1,170 @@
+// internal/logfetcher/ecs.go
+
+package logfetcher
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	schemaLegacy = "legacy"
+	schemaECS    = "ecs"
+
+	ecsVersion = "8.11.0"
+)
+
+// schemaForIndex indeks adına göre kullanılacak çıktı şemasını döndürür.
+// ES_INDEX_SCHEMAS desenleri (örn. "10_0_0_1-*=ecs") OUTPUT_SCHEMA'dan önceliklidir;
+// birden çok desen uyarsa yazıldığı sırada ilk eşleşen kazanır. Index template'leri aynı
+// sırayı öncelikle kurar.
+func schemaForIndex(indexName string) string {
+	return indexSchema(config.GetConfig(), indexName)
+}
+
+func indexSchema(cfg *config.Config, indexName string) string {
+	for _, rule := range indexSchemaRules(cfg) {
+		if ok, _ := path.Match(rule.Key, indexName); ok {
+			return normalizeSchemaName(rule.Value)
+		}
+	}
+	return normalizeSchemaName(cfg.OutputSchema)
+}
+
+// indexSchemaRules index template deseni olarak da kullanılabilen ES_INDEX_SCHEMAS
+// kurallarıdır. ES index_patterns yalnızca * joker karakterini tanır; diğerleri atlanır ki
+// belge ile şablon farklı şemada kalmasın.
+func indexSchemaRules(cfg *config.Config) []config.KeyValue {
+	var out []config.KeyValue
+	for _, rule := range cfg.IndexSchemas {
+		if strings.ContainsAny(rule.Key, "?[\\") {
+			continue
+		}
+		if len(out) == maxIndexSchemas {
+			break
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+func normalizeSchemaName(name string) string {
+	if strings.EqualFold(strings.TrimSpace(name), schemaECS) {
+		return schemaECS
+	}
+	return schemaLegacy
+}
+
+// encodeDoc belgeyi seçilen şemaya göre indekslenecek yapıya çevirir.
+func encodeDoc(doc ParsedLog, schema string) any {
+	if schema == schemaECS {
+		return toECS(doc)
+	}
+	return doc
+}
+
+// toECS ParsedLog'u ECS 8.x alan adlarına çevirir. ECS karşılığı olmayan alanlar
+// "tedalogger.*" altında tutulur.
+func toECS(doc ParsedLog) map[string]any {
+	m := make(map[string]any)
+
+	if !doc.Timestamp.IsZero() {
+		m["@timestamp"] = doc.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	setECS(m, "ecs.version", ecsVersion)
+	setECS(m, "message", doc.RawMessage)
+	setECS(m, "event.kind", "event")
+	setECS(m, "event.category", []string{"network"})
+	setECS(m, "event.action", doc.EventAction)
+	setECS(m, "event.outcome", doc.EventOutcome)
+
+	setECS(m, "source.ip", doc.SrcIP)
+	setECS(m, "source.port", ecsPort(doc.SrcPort))
+	setECS(m, "source.mac", doc.SrcMac)
+	setECS(m, "source.domain", doc.SrcHostname)
+	setECS(m, "destination.ip", doc.DstIP)
+	setECS(m, "destination.port", ecsPort(doc.DstPort))
+	setECS(m, "destination.domain", doc.Hostname)
+
+	setECS(m, "url.full", doc.URL)
+	setECS(m, "url.domain", doc.Hostname)
+
+	setECS(m, "user.name", doc.User)
+	setECS(m, "user.full_name", doc.UserDisplayName)
+	if len(doc.UserGroups) > 0 {
+		setECS(m, "user.group.name", doc.UserGroups)
+	}
+
+	setECS(m, "network.direction", doc.NetworkDirection)
+	setECS(m, "network.transport", doc.NetworkProtocol)
+
+	setECS(m, "observer.name", firstNonEmpty(doc.DevName, doc.NASName))
+	setECS(m, "observer.vendor", doc.Brand)
+	setECS(m, "observer.serial_number", firstNonEmpty(doc.DevID, doc.DeviceID))
+	setECS(m, "observer.ingress.interface.name", doc.SrcIntf)
+	setECS(m, "observer.hostname", doc.FromHost)
+
+	setECS(m, "rule.name", doc.PolicyName)
+
+	if len(doc.Tags) > 0 {
+		setECS(m, "tags", doc.Tags)
+	}
+	setECS(m, "tedalogger.nas_name", doc.NASName)
+	setECS(m, "tedalogger.declared_brand", doc.DeclaredBrand)
+	setECS(m, "tedalogger.detected_brand", doc.DetectedBrand)
+	setECS(m, "tedalogger.url_category", doc.URLCategory)
+	setECS(m, "tedalogger.action_raw", doc.ActionRaw)
+	setECS(m, "tedalogger.action", doc.Action)
+	setECS(m, "tedalogger.proto_raw", doc.ProtoRaw)
+	setECS(m, "tedalogger.direction_raw", doc.DirectionRaw)
+	setECS(m, "tedalogger.taxonomy_version", doc.TaxonomyVersion)
+	setECS(m, "tedalogger.asset.owner", doc.AssetOwner)
+	setECS(m, "tedalogger.asset.department", doc.AssetDepartment)
+	setECS(m, "tedalogger.user.department", doc.UserDepartment)
+	setECS(m, "tedalogger.raw_charset", doc.RawCharset)
+	setECS(m, "tedalogger.raw_message_b64", doc.RawMessageB64)
+	if doc.InvalidBytes > 0 {
+		setECS(m, "tedalogger.invalid_bytes", doc.InvalidBytes)
+	}
+
+	return m
+}
+
+// setECS noktalı yolu iç içe map'lere açarak değeri yazar; boş değerler atlanır.
+func setECS(m map[string]any, dotted string, val any) {
+	switch v := val.(type) {
+	case nil:
+		return
+	case string:
+		if v == "" {
+			return
+		}
+	}
+
+	parts := strings.Split(dotted, ".")
+	cur := m
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			cur[p] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = val
+}
+
+func ecsPort(s string) any {
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil
+	}
+	return n
+}
@@ -0,0 +1,395 @@
+// internal/logfetcher/ecs_migrate.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"tedalogger-logfetcher/config"
+)
+
+const ecsPipelineID = "tedalogger-legacy-to-ecs"
+
+// legacyToECSFields legacy alan adlarının ECS karşılıklarıdır; toECS ile aynı eşlemeyi izler.
+var legacyToECSFields = [][2]string{
+	{"timestamp", "@timestamp"},
+	{"raw_message", "message"},
+	{"src_ip", "source.ip"},
+	{"src_port", "source.port"},
+	{"src_mac", "source.mac"},
+	{"src_hostname", "source.domain"},
+	{"dst_ip", "destination.ip"},
+	{"dst_port", "destination.port"},
+	{"url", "url.full"},
+	{"hostname", "url.domain"},
+	{"user", "user.name"},
+	{"user_display_name", "user.full_name"},
+	{"user_groups", "user.group.name"},
+	{"event_action", "event.action"},
+	{"event_outcome", "event.outcome"},
+	{"network_direction", "network.direction"},
+	{"network_protocol", "network.transport"},
+	{"dev_name", "observer.name"},
+	{"brand", "observer.vendor"},
+	{"dev_id", "observer.serial_number"},
+	{"device_id", "observer.serial_number"},
+	{"src_intf", "observer.ingress.interface.name"},
+	{"from_host", "observer.hostname"},
+	{"policy_name", "rule.name"},
+	{"nas_name", "tedalogger.nas_name"},
+	{"declared_brand", "tedalogger.declared_brand"},
+	{"detected_brand", "tedalogger.detected_brand"},
+	{"url_category", "tedalogger.url_category"},
+	{"action_raw", "tedalogger.action_raw"},
+	{"action", "tedalogger.action"},
+	{"proto_raw", "tedalogger.proto_raw"},
+	{"direction_raw", "tedalogger.direction_raw"},
+	{"taxonomy_version", "tedalogger.taxonomy_version"},
+	{"asset_owner", "tedalogger.asset.owner"},
+	{"asset_department", "tedalogger.asset.department"},
+	{"user_department", "tedalogger.user.department"},
+	{"raw_charset", "tedalogger.raw_charset"},
+	{"raw_message_b64", "tedalogger.raw_message_b64"},
+	{"invalid_bytes", "tedalogger.invalid_bytes"},
+}
+
+func ecsPipelineBody() ([]byte, error) {
+	var processors []map[string]any
+	for _, f := range legacyToECSFields {
+		processors = append(processors, map[string]any{
+			"rename": map[string]any{
+				"field":          f[0],
+				"target_field":   f[1],
+				"ignore_missing": true,
+				"ignore_failure": true,
+			},
+		})
+	}
+	for _, f := range []string{"source.port", "destination.port"} {
+		processors = append(processors, map[string]any{
+			"convert": map[string]any{
+				"field":          f,
+				"type":           "integer",
+				"ignore_missing": true,
+				"ignore_failure": true,
+			},
+		})
+	}
+	processors = append(processors,
+		map[string]any{"set": map[string]any{
+			"field":              "observer.name",
+			"copy_from":          "tedalogger.nas_name",
+			"override":           false,
+			"ignore_empty_value": true,
+		}},
+		map[string]any{"set": map[string]any{"field": "ecs.version", "value": ecsVersion}},
+		map[string]any{"set": map[string]any{"field": "event.kind", "value": "event"}},
+		map[string]any{"set": map[string]any{"field": "event.category", "value": []string{"network"}}},
+	)
+
+	return json.Marshal(map[string]any{
+		"description": "tedalogger legacy layout -> ECS " + ecsVersion,
+		"processors":  processors,
+	})
+}
+
+func installECSPipeline(es *elasticsearch.Client) error {
+	body, err := ecsPipelineBody()
+	if err != nil {
+		return err
+	}
+	res, err := es.Ingest.PutPipeline(ecsPipelineID, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("put pipeline error: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("put pipeline response error: %s", res.String())
+	}
+	return nil
+}
+
+// MigrateIndicesToECS desene uyan legacy indeksleri ECS pipeline'ı ile "<indeks>-ecs"
+// indekslerine kopyalar. deleteSource verilirse eski indeks, kopya doğrulandıktan sonra
+// silinir ve eski ad yeni indekse alias olarak bağlanır; böylece mevcut sorgular çalışmaya
+// devam eder. Hâlâ yazılabilen günlerin indeksleri bu durumda atlanır (bkz. migrateIndex).
+func MigrateIndicesToECS(pattern string, deleteSource bool) error {
+	es, err := connectES()
+	if err != nil {
+		return err
+	}
+	indices, err := listIndices(es, pattern)
+	if err != nil {
+		return err
+	}
+
+	// Hedefler şablonlardan ayar ve ILM politikasını alır; kaynakların NAS'ları desenlere
+	// eklenir ki "-ecs" indeksleri de kapsansın.
+	for _, src := range indices {
+		if nas, ok := nasFromDailyIndex(src); ok {
+			addTemplateNAS([]NAS{{Nasname: nas}})
+		}
+	}
+	if err := InstallTemplates(es); err != nil {
+		return err
+	}
+	if err := installECSPipeline(es); err != nil {
+		return err
+	}
+
+	for _, src := range indices {
+		if strings.HasSuffix(src, "-ecs") {
+			continue
+		}
+		dst := src + "-ecs"
+		if err := createECSIndex(es, dst); err != nil {
+			return fmt.Errorf("create %s: %w", dst, err)
+		}
+		if err := migrateIndex(es, src, dst, ecsPipelineID, "", deleteSource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createECSIndex hedefi ECS mapping'iyle açar; ES_TEMPLATE_PATTERNS hedefi kapsamasa da belgeler
+// dinamik mapping'e düşmez. İndeks zaten varsa dokunulmaz.
+func createECSIndex(es *elasticsearch.Client, index string) error {
+	body, _ := json.Marshal(map[string]any{"mappings": mappingsFor(schemaECS)})
+	res, err := es.Indices.Create(index, es.Indices.Create.WithBody(bytes.NewReader(body)))
+	if err != nil {
+		return err
+	}
+	data, err := readBody(res)
+	if err != nil {
+		return err
+	}
+	if res.IsError() && !bytes.Contains(data, []byte("resource_already_exists_exception")) {
+		return fmt.Errorf("%s %s", res.Status(), data)
+	}
+	return nil
+}
+
+// migrateIndex src'yi dst'ye kopyalar. deleteSource verilirse:
+//   - logfetcher'ın hâlâ yazabileceği günlerin indeksleri (INDEX_MAX_PAST, en az bir gün)
+//     ve adından günü okunamayan indeksler taşınmaz,
+//   - kopyalamadan önce src yazmaya kapatılır ki kopya ile silme arasında belge kaybolmasın,
+//   - reindex sonucu ve iki taraftaki _count tutmadıkça src silinmez; salt okunur kalır.
+func migrateIndex(es *elasticsearch.Client, src, dst, pipeline, opType string, deleteSource bool) error {
+	if !deleteSource {
+		log.Printf("[Migrate] Reindexing %s -> %s", src, dst)
+		if _, err := reindexWithPipeline(es, src, dst, pipeline, opType); err != nil {
+			return fmt.Errorf("reindex %s: %w", src, err)
+		}
+		return nil
+	}
+
+	until, live := indexWritableUntil(src, time.Now(), indexLocation(), config.GetConfig().IndexMaxPast)
+	if live {
+		if until.IsZero() {
+			log.Printf("[Migrate] %s has no daily index date, not deleting it; skipping", src)
+		} else {
+			log.Printf("[Migrate] %s may still receive logs until %s, skipping", src, until.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	if err := blockWrites(es, src); err != nil {
+		return fmt.Errorf("block writes %s: %w", src, err)
+	}
+	srcCount, err := countDocs(es, src)
+	if err != nil {
+		return fmt.Errorf("count %s: %w", src, err)
+	}
+	dstBefore, err := countDocs(es, dst)
+	if err != nil {
+		return fmt.Errorf("count %s: %w", dst, err)
+	}
+
+	log.Printf("[Migrate] Reindexing %s -> %s", src, dst)
+	r, err := reindexWithPipeline(es, src, dst, pipeline, opType)
+	if err != nil {
+		return fmt.Errorf("reindex %s (left read-only): %w", src, err)
+	}
+	dstAfter, err := countDocs(es, dst)
+	if err != nil {
+		return fmt.Errorf("count %s: %w", dst, err)
+	}
+	if err := verifyReindex(r, srcCount, dstBefore, dstAfter); err != nil {
+		return fmt.Errorf("verify %s -> %s (left read-only): %w", src, dst, err)
+	}
+
+	res, err := es.Indices.Delete([]string{src})
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", src, err)
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("delete %s: %s", src, res.String())
+	}
+
+	res, err = es.Indices.PutAlias([]string{dst}, src)
+	if err != nil {
+		return fmt.Errorf("alias %s: %w", src, err)
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("alias %s: %s", src, res.String())
+	}
+	return nil
+}
+
+// indexWritableUntil günlük indeksin logfetcher tarafından en son ne zaman yazılabileceğini
+// döndürür: günün sonu artı INDEX_MAX_PAST (sınırsızsa ya da daha kısaysa bir gün). Adından
+// gün okunamayan indeksler her zaman yazılabilir sayılır.
+func indexWritableUntil(index string, now time.Time, loc *time.Location, maxPast time.Duration) (time.Time, bool) {
+	_, day, ok := splitDailyIndex(index, loc)
+	if !ok {
+		return time.Time{}, true
+	}
+	grace := maxPast
+	if grace < 24*time.Hour {
+		grace = 24 * time.Hour
+	}
+	until := day.AddDate(0, 0, 1).Add(grace)
+	return until, now.Before(until)
+}
+
+// verifyReindex kaynak indeksin tüm belgelerinin hedefe ulaştığını doğrular. Hedef data
+// stream ise başka indekslerden ve canlı yazımlardan belge alabilir; bu yüzden hedefin
+// yalnızca en az reindex'in eklediği kadar büyümesi beklenir.
+func verifyReindex(r reindexResult, srcCount, dstBefore, dstAfter int) error {
+	switch {
+	case r.Total != srcCount:
+		return fmt.Errorf("reindex saw %d documents, source has %d", r.Total, srcCount)
+	case r.Created+r.Updated != srcCount:
+		return fmt.Errorf("reindex wrote %d of %d documents", r.Created+r.Updated, srcCount)
+	case dstAfter < dstBefore+r.Created || dstAfter < srcCount:
+		return fmt.Errorf("destination has %d documents (was %d), expected %d more", dstAfter, dstBefore, r.Created)
+	}
+	return nil
+}
+
+func blockWrites(es *elasticsearch.Client, index string) error {
+	res, err := es.Indices.AddBlock([]string{index}, "write")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("%s", res.String())
+	}
+	return nil
+}
+
+// countDocs indeksi yenileyip belge sayısını döndürür; indeks yoksa 0'dır.
+func countDocs(es *elasticsearch.Client, index string) (int, error) {
+	res, err := es.Indices.Refresh(es.Indices.Refresh.WithIndex(index))
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	if res.StatusCode == 404 {
+		return 0, nil
+	}
+	if res.IsError() {
+		return 0, fmt.Errorf("refresh: %s", res.String())
+	}
+
+	res, err = es.Count(es.Count.WithIndex(index))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, fmt.Errorf("count: %s", res.String())
+	}
+	var r struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return 0, fmt.Errorf("count parse error: %w", err)
+	}
+	return r.Count, nil
+}
+
+func listIndices(es *elasticsearch.Client, pattern string) ([]string, error) {
+	res, err := es.Cat.Indices(
+		es.Cat.Indices.WithIndex(pattern),
+		es.Cat.Indices.WithH("index"),
+		es.Cat.Indices.WithFormat("json"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cat indices error: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("cat indices response error: %s", res.String())
+	}
+
+	var rows []struct {
+		Index string `json:"index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("cat indices parse error: %w", err)
+	}
+	out := make([]string, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r.Index)
+	}
+	return out, nil
+}
+
+type reindexResult struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// reindexWithPipeline src'yi dst'ye kopyalar. Data stream hedefleri opType "create" ister.
+func reindexWithPipeline(es *elasticsearch.Client, src, dst, pipeline, opType string) (reindexResult, error) {
+	dest := map[string]any{"index": dst}
+	if pipeline != "" {
+		dest["pipeline"] = pipeline
+	}
+	if opType != "" {
+		dest["op_type"] = opType
+	}
+	body, _ := json.Marshal(map[string]any{
+		"source": map[string]any{"index": src},
+		"dest":   dest,
+	})
+
+	res, err := es.Reindex(
+		bytes.NewReader(body),
+		es.Reindex.WithContext(context.Background()),
+		es.Reindex.WithWaitForCompletion(true),
+	)
+	if err != nil {
+		return reindexResult{}, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return reindexResult{}, fmt.Errorf("reindex response error: %s", res.String())
+	}
+
+	var parsed struct {
+		reindexResult
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return reindexResult{}, fmt.Errorf("reindex parse error: %w", err)
+	}
+	if len(parsed.Failures) > 0 {
+		return reindexResult{}, fmt.Errorf("reindex had %d failures, first: %s", len(parsed.Failures), parsed.Failures[0])
+	}
+	log.Printf("[Reindex] %s -> %s: %d created, %d updated of %d documents", src, dst, parsed.Created, parsed.Updated, parsed.Total)
+	return parsed.reindexResult, nil
+}
@@ -0,0 +1,54 @@
+package logfetcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyReindex(t *testing.T) {
+	tests := []struct {
+		name                          string
+		r                             reindexResult
+		srcCount, dstBefore, dstAfter int
+		ok                            bool
+	}{
+		{"new index", reindexResult{Total: 10, Created: 10}, 10, 0, 10, true},
+		{"rerun into existing index", reindexResult{Total: 10, Updated: 10}, 10, 10, 10, true},
+		{"stream with live writes", reindexResult{Total: 10, Created: 10}, 10, 500, 530, true},
+		{"source grew", reindexResult{Total: 10, Created: 10}, 12, 0, 10, false},
+		{"partial write", reindexResult{Total: 10, Created: 9}, 10, 0, 9, false},
+		{"destination short", reindexResult{Total: 10, Created: 10}, 10, 500, 505, false},
+		{"empty source", reindexResult{}, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyReindex(tt.r, tt.srcCount, tt.dstBefore, tt.dstAfter)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIndexWritableUntil(t *testing.T) {
+	loc := time.FixedZone("TRT", 3*60*60)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, loc)
+	tests := []struct {
+		index   string
+		maxPast time.Duration
+		live    bool
+	}{
+		{"10_0_0_1-19-10-2026", 6 * 24 * time.Hour, true},
+		{"10_0_0_1-13-10-2026", 6 * 24 * time.Hour, true},
+		{"10_0_0_1-12-10-2026", 6 * 24 * time.Hour, false},
+		{"10_0_0_1-18-10-2026", 0, true},
+		{"10_0_0_1-17-10-2026", 0, false},
+		{"10_0_0_1-17-10-2026", time.Hour, false},
+		{"10_0_0_1-current", 6 * 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		if _, live := indexWritableUntil(tt.index, now, loc, tt.maxPast); live != tt.live {
+			t.Errorf("indexWritableUntil(%s, %s) live = %v, want %v", tt.index, tt.maxPast, live, tt.live)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package logfetcher
+
+import (
+	"testing"
+
+	"tedalogger-logfetcher/config"
+)
+
+func TestIndexSchemaFirstMatch(t *testing.T) {
+	cfg := &config.Config{
+		OutputSchema: "legacy",
+		IndexSchemas: []config.KeyValue{
+			{Key: "10_0_0_1-*", Value: "legacy"},
+			{Key: "10_0_0_?-*", Value: "legacy"}, // şablonu kurulamaz, yok sayılır
+			{Key: "10_*", Value: "ecs"},
+		},
+	}
+	tests := map[string]string{
+		"10_0_0_1-2026-10-19": schemaLegacy,
+		"10_0_0_2-2026-10-19": schemaECS,
+		"10_9_9_9-2026-10-19": schemaECS,
+		"192_168_0_1-2026":    schemaLegacy,
+	}
+	for index, want := range tests {
+		if got := indexSchema(cfg, index); got != want {
+			t.Errorf("indexSchema(%s) = %s, want %s", index, got, want)
+		}
+	}
+}
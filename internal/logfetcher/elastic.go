@@ -3,11 +3,21 @@
 package logfetcher
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"tedalogger-logfetcher/config"
@@ -16,13 +26,9 @@ import (
 func connectES() (*elasticsearch.Client, error) {
 	cfg := config.GetConfig()
 
-	esConfig := elasticsearch.Config{
-		Addresses: []string{cfg.ElasticURL},
-	}
-
-	if cfg.ElasticUser != "" && cfg.ElasticPass != "" {
-		esConfig.Username = cfg.ElasticUser
-		esConfig.Password = cfg.ElasticPass
+	esConfig, err := esClientConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	es, err := elasticsearch.NewClient(esConfig)
@@ -40,28 +46,237 @@ func connectES() (*elasticsearch.Client, error) {
 		return nil, fmt.Errorf("elasticsearch error response: %s", res.String())
 	}
 
-	log.Printf("Connected to Elasticsearch at %s (Auth? %v)", cfg.ElasticURL, cfg.ElasticUser != "")
+	target := strings.Join(cfg.ElasticURLs, ",")
+	if cfg.ElasticCloudID != "" {
+		target = "cloud id " + strings.SplitN(cfg.ElasticCloudID, ":", 2)[0]
+	}
+	log.Printf("Connected to Elasticsearch at %s (Auth: %s)", target, esAuthMethod(cfg))
 	return es, nil
 }
 
-func indexLogToES(es *elasticsearch.Client, doc ParsedLog, indexName string) error {
-	data, err := json.Marshal(doc)
+// esClientConfig ELASTIC_* ayarlarından istemci yapılandırmasını kurar.
+func esClientConfig(cfg *config.Config) (elasticsearch.Config, error) {
+	esConfig := elasticsearch.Config{
+		Addresses:           cfg.ElasticURLs,
+		CloudID:             cfg.ElasticCloudID,
+		APIKey:              cfg.ElasticAPIKey,
+		ServiceToken:        cfg.ElasticServiceToken,
+		CompressRequestBody: cfg.ElasticCompress,
+		MaxRetries:          cfg.ElasticMaxRetries,
+		DisableRetry:        cfg.ElasticMaxRetries <= 0,
+	}
+	if len(cfg.ElasticURLs) == 0 && cfg.ElasticCloudID == "" {
+		return esConfig, fmt.Errorf("ELASTIC_URL or ELASTIC_CLOUD_ID is required")
+	}
+
+	if cfg.ElasticUser != "" && cfg.ElasticPass != "" {
+		esConfig.Username = cfg.ElasticUser
+		esConfig.Password = cfg.ElasticPass
+	}
+
+	if cfg.ElasticSniff {
+		// Elastic Cloud'da düğümler bir yük dengeleyicinin arkasındadır; keşif anlamsızdır.
+		if cfg.ElasticCloudID != "" {
+			log.Printf("ELASTIC_SNIFF ignored with ELASTIC_CLOUD_ID")
+		} else {
+			esConfig.DiscoverNodesOnStart = true
+			esConfig.DiscoverNodesInterval = cfg.ElasticSniffInterval
+		}
+	}
+
+	retryOn, err := retryStatuses(cfg)
 	if err != nil {
-		return fmt.Errorf("json marshal error: %w", err)
+		return esConfig, err
 	}
+	esConfig.RetryOnStatus = retryOn
+	esConfig.RetryBackoff = retryBackoff(cfg)
 
-	res, err := es.Index(
-		indexName,
-		strings.NewReader(string(data)),
-		es.Index.WithContext(context.Background()),
-	)
+	tc, err := clusterTLSConfig("Elasticsearch", "ELASTIC_CA_FINGERPRINT",
+		cfg.ElasticCAFile, cfg.ElasticFingerprint, cfg.ElasticCertFile, cfg.ElasticKeyFile)
 	if err != nil {
-		return fmt.Errorf("ES index error: %w", err)
+		return esConfig, err
 	}
-	defer res.Body.Close()
+	esConfig.Transport = clusterTransport(cfg, tc)
+	return esConfig, nil
+}
 
-	if res.IsError() {
-		return fmt.Errorf("ES response error: %s", res.String())
+// retryStatuses ELASTIC_RETRY_ON_STATUS listesini çözer; boş liste istemcinin varsayılanıdır.
+func retryStatuses(cfg *config.Config) ([]int, error) {
+	var codes []int
+	for _, v := range cfg.ElasticRetryOnStatus {
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ELASTIC_RETRY_ON_STATUS value %q", v)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func retryBackoff(cfg *config.Config) func(attempt int) time.Duration {
+	if cfg.ElasticRetryBackoff <= 0 {
+		return nil
+	}
+	return func(attempt int) time.Duration {
+		return expBackoff(attempt-1, cfg.ElasticRetryBackoff, cfg.ElasticRetryBackoffMax)
+	}
+}
+
+// clusterTransport istek zaman aşımı ve TLS ayarlı HTTP transport'u döndürür.
+func clusterTransport(cfg *config.Config, tc *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cfg.ElasticRequestTimeout
+	if tc != nil {
+		transport.TLSClientConfig = tc
+	}
+	if tc != nil && tc.InsecureSkipVerify {
+		// Parmak izine sabitlenmiş CA ile doğrulamada sertifikanın bağlanılan adrese ait
+		// olduğu da denetlenir. IP ile bağlanırken SNI gönderilmediğinden ConnectionState'te
+		// sunucu adı boş kalır; adres burada bağlantıya işlenir.
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			c := tc.Clone()
+			c.ServerName = host
+			verify := c.VerifyConnection
+			c.VerifyConnection = func(cs tls.ConnectionState) error {
+				cs.ServerName = host
+				return verify(cs)
+			}
+			d := &tls.Dialer{NetDialer: dialer, Config: c}
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+	return transport
+}
+
+// clusterTLSConfig CA dosyası, sertifika parmak izi ve istemci sertifikasını tek bir TLS
+// yapılandırmasında birleştirir. İstemcinin kendi parmak izi desteği istemci sertifikasını
+// yok saydığı için doğrulama burada yapılır. product hata mesajlarında, fingerprintEnv
+// geçersiz parmak izi bildiriminde kullanılır.
+func clusterTLSConfig(product, fingerprintEnv, caFile, fingerprint, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && fingerprint == "" && certFile == "" {
+		return nil, nil
+	}
+
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s CA read error: %w", product, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s CA file has no valid certificates: %s", product, caFile)
+		}
+		tc.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s client certificate error: %w", product, err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if fingerprint != "" {
+		want, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+		if err != nil || len(want) != sha256.Size {
+			return nil, fmt.Errorf("invalid %s %q", fingerprintEnv, fingerprint)
+		}
+		if caFile != "" {
+			// Zincir CA dosyasıyla doğrulandı; parmak izi doğrulanmış zincirde aranır.
+			tc.VerifyConnection = func(cs tls.ConnectionState) error {
+				for _, chain := range cs.VerifiedChains {
+					for _, cert := range chain {
+						if sum := sha256.Sum256(cert.Raw); bytes.Equal(sum[:], want) {
+							return nil
+						}
+					}
+				}
+				return fmt.Errorf("%s certificate fingerprint mismatch", product)
+			}
+		} else {
+			tc.InsecureSkipVerify = true
+			tc.VerifyConnection = func(cs tls.ConnectionState) error {
+				return verifyPinned(product, want, cs)
+			}
+		}
+	}
+	return tc, nil
+}
+
+// verifyPinned CA dosyası olmadan parmak izi doğrulamasıdır. Parmak izi sunucu sertifikasına
+// aitse yeterlidir. ES'in ilk kurulumda verdiği CA'ya aitse sunucu sertifikası yalnızca o
+// CA'ya kök olarak zincirlenip sunucu adıyla doğrulanır: CA sertifikası herkese açık
+// olduğundan zincirde bulunması tek başına bir şey kanıtlamaz.
+func verifyPinned(product string, want []byte, cs tls.ConnectionState) error {
+	certs := cs.PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("%s sent no certificate", product)
+	}
+	if sum := sha256.Sum256(certs[0].Raw); bytes.Equal(sum[:], want) {
+		return nil
+	}
+	for _, ca := range certs[1:] {
+		if sum := sha256.Sum256(ca.Raw); !bytes.Equal(sum[:], want) || !ca.IsCA {
+			continue
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			if cert != ca {
+				intermediates.AddCert(cert)
+			}
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       cs.ServerName,
+		})
+		if err != nil {
+			return fmt.Errorf("%s certificate not valid for pinned CA: %w", product, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s certificate fingerprint mismatch", product)
+}
+
+func esAuthMethod(cfg *config.Config) string {
+	switch {
+	case cfg.ElasticAPIKey != "":
+		return "api key"
+	case cfg.ElasticServiceToken != "":
+		return "service token"
+	case cfg.ElasticUser != "":
+		return "basic"
+	default:
+		return "none"
+	}
+}
+
+// esIndexError Status=0 ise bağlantı/transport hatasıdır.
+type esIndexError struct {
+	Status int
+	Msg    string
+}
+
+func (e *esIndexError) Error() string {
+	if e.Status == 0 {
+		return "ES index error: " + e.Msg
+	}
+	return fmt.Sprintf("ES response error (%d): %s", e.Status, e.Msg)
+}
+
+// isRetryableIndexError geçici hatalarda (bağlantı, 429, 5xx) true döner; mapping
+// hatası gibi aynı belgeyle tekrar denendiğinde yine başarısız olacak durumlarda false.
+func isRetryableIndexError(err error) bool {
+	var ie *esIndexError
+	if !errors.As(err, &ie) {
+		return false
 	}
-	return nil
+	return ie.Status == 0 || ie.Status == 429 || ie.Status >= 500
 }
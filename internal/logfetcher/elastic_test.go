@@ -0,0 +1,141 @@
+package logfetcher
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, isCA bool, ips ...net.IP) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           ips,
+	}
+	if !isCA {
+		tmpl.DNSNames = []string{cn}
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func fingerprintOf(c *x509.Certificate) []byte {
+	sum := sha256.Sum256(c.Raw)
+	return sum[:]
+}
+
+func TestVerifyPinned(t *testing.T) {
+	ca := newTestCert(t, "es-ca", nil, true)
+	leaf := newTestCert(t, "es.local", ca, false)
+	rogue := newTestCert(t, "es.local", nil, false)
+	otherCA := newTestCert(t, "other-ca", nil, true)
+	otherLeaf := newTestCert(t, "es.local", otherCA, false)
+
+	tests := []struct {
+		name   string
+		pin    []byte
+		chain  []*x509.Certificate
+		server string
+		ok     bool
+	}{
+		{"leaf pinned", fingerprintOf(leaf.cert), []*x509.Certificate{leaf.cert, ca.cert}, "", true},
+		{"ca pinned", fingerprintOf(ca.cert), []*x509.Certificate{leaf.cert, ca.cert}, "es.local", true},
+		{"ca pinned wrong host", fingerprintOf(ca.cert), []*x509.Certificate{leaf.cert, ca.cert}, "evil.local", false},
+		{"ca appended to rogue leaf", fingerprintOf(ca.cert), []*x509.Certificate{rogue.cert, ca.cert}, "es.local", false},
+		{"ca appended to other chain", fingerprintOf(ca.cert), []*x509.Certificate{otherLeaf.cert, otherCA.cert, ca.cert}, "es.local", false},
+		{"non-ca pinned in chain", fingerprintOf(rogue.cert), []*x509.Certificate{leaf.cert, rogue.cert}, "es.local", false},
+		{"no match", fingerprintOf(otherCA.cert), []*x509.Certificate{leaf.cert, ca.cert}, "es.local", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyPinned("Elasticsearch", tt.pin, tls.ConnectionState{
+				PeerCertificates: tt.chain,
+				ServerName:       tt.server,
+			})
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+// TestPinnedTransport IP adresiyle bağlanırken de sabitlenmiş CA ile sunucu adının
+// doğrulandığını sınar.
+func TestPinnedTransport(t *testing.T) {
+	ca := newTestCert(t, "es-ca", nil, true)
+	good := newTestCert(t, "es.local", ca, false, net.ParseIP("127.0.0.1"))
+	wrongIP := newTestCert(t, "es.local", ca, false, net.ParseIP("10.0.0.1"))
+
+	for _, tt := range []struct {
+		name string
+		leaf *testCert
+		ok   bool
+	}{
+		{"matching ip", good, true},
+		{"other ip", wrongIP, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			srv.TLS = &tls.Config{Certificates: []tls.Certificate{{
+				Certificate: [][]byte{tt.leaf.cert.Raw, ca.cert.Raw},
+				PrivateKey:  tt.leaf.key,
+			}}}
+			srv.StartTLS()
+			defer srv.Close()
+
+			tc, err := clusterTLSConfig("Elasticsearch", "ELASTIC_CA_FINGERPRINT", "",
+				hex.EncodeToString(fingerprintOf(ca.cert)), "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: clusterTransport(&config.Config{ElasticRequestTimeout: 5 * time.Second}, tc)}
+			res, err := client.Get(srv.URL)
+			if err == nil {
+				res.Body.Close()
+			}
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
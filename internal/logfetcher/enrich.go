@@ -0,0 +1,77 @@
+// internal/logfetcher/enrich.go
+
+package logfetcher
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"tedalogger-logfetcher/config"
+)
+
+type enricher struct {
+	assets *assetStore
+	ldap   *ldapResolver
+}
+
+var (
+	enricherOnce sync.Once
+	enricherInst *enricher
+)
+
+func getEnricher() *enricher {
+	enricherOnce.Do(func() {
+		cfg := config.GetConfig()
+		e := &enricher{}
+		if cfg.AssetCSVDir != "" {
+			e.assets = newAssetStore(cfg.AssetCSVDir)
+			log.Printf("Asset enrichment enabled (dir=%s)", cfg.AssetCSVDir)
+		}
+		if cfg.LDAPURL != "" {
+			e.ldap = newLDAPResolver(cfg)
+			log.Printf("LDAP enrichment enabled (url=%s)", cfg.LDAPURL)
+		}
+		enricherInst = e
+	})
+	return enricherInst
+}
+
+// enrichDoc, belgeyi NAS'a ait asset listesi ve dizin servisi bilgileriyle doldurur.
+// Kaynaklara erişilemezse belge olduğu gibi bırakılır.
+func enrichDoc(doc *ParsedLog, nasName string) {
+	e := getEnricher()
+
+	if e.assets != nil {
+		if a, ok := e.assets.lookup(nasName, doc.SrcIP, doc.SrcMac); ok {
+			doc.SrcHostname = a.Hostname
+			doc.AssetOwner = a.Owner
+			doc.AssetDepartment = a.Department
+		}
+	}
+
+	if e.ldap != nil && doc.User != "" {
+		info, err := e.ldap.resolve(normalizeUserName(doc.User))
+		if err != nil {
+			log.Printf("LDAP lookup error (user=%s): %v", doc.User, err)
+			return
+		}
+		if info != nil {
+			doc.UserDisplayName = info.DisplayName
+			doc.UserDepartment = info.Department
+			doc.UserGroups = info.Groups
+		}
+	}
+}
+
+// normalizeUserName, DOMAIN\user ve user@domain biçimlerinden yalın hesap adını çıkarır.
+func normalizeUserName(user string) string {
+	u := strings.TrimSpace(user)
+	if i := strings.LastIndex(u, `\`); i >= 0 {
+		u = u[i+1:]
+	}
+	if i := strings.Index(u, "@"); i >= 0 {
+		u = u[:i]
+	}
+	return strings.ToLower(u)
+}
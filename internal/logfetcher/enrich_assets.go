@@ -0,0 +1,176 @@
+// internal/logfetcher/enrich_assets.go
+
+package logfetcher
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const assetReloadInterval = 30 * time.Second
+
+type assetInfo struct {
+	Hostname   string
+	Owner      string
+	Department string
+}
+
+type nasAssets struct {
+	byIP      map[string]assetInfo
+	byMAC     map[string]assetInfo
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// assetStore, her NAS için <dir>/<nasName>.csv dosyasını okur ve
+// dosya değiştikçe yeniden yükler.
+type assetStore struct {
+	dir string
+
+	mu    sync.Mutex
+	byNAS map[string]*nasAssets
+}
+
+func newAssetStore(dir string) *assetStore {
+	return &assetStore{
+		dir:   dir,
+		byNAS: make(map[string]*nasAssets),
+	}
+}
+
+func (s *assetStore) lookup(nasName, ip, mac string) (assetInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	na := s.refresh(nasName)
+	if na == nil {
+		return assetInfo{}, false
+	}
+	if ip != "" {
+		if a, ok := na.byIP[ip]; ok {
+			return a, true
+		}
+	}
+	if mac != "" {
+		if a, ok := na.byMAC[normalizeMAC(mac)]; ok {
+			return a, true
+		}
+	}
+	return assetInfo{}, false
+}
+
+func (s *assetStore) refresh(nasName string) *nasAssets {
+	na := s.byNAS[nasName]
+	if na != nil && time.Since(na.checkedAt) < assetReloadInterval {
+		return na
+	}
+
+	path := filepath.Join(s.dir, nasName+".csv")
+	st, err := os.Stat(path)
+	if err != nil {
+		if na == nil {
+			na = &nasAssets{}
+			s.byNAS[nasName] = na
+		}
+		na.checkedAt = time.Now()
+		return na
+	}
+
+	if na != nil && st.ModTime().Equal(na.modTime) {
+		na.checkedAt = time.Now()
+		return na
+	}
+
+	loaded, err := loadAssetCSV(path)
+	if err != nil {
+		log.Printf("Asset CSV load error (%s): %v", path, err)
+		if na == nil {
+			na = &nasAssets{}
+			s.byNAS[nasName] = na
+		}
+		na.checkedAt = time.Now()
+		return na
+	}
+	loaded.modTime = st.ModTime()
+	loaded.checkedAt = time.Now()
+	s.byNAS[nasName] = loaded
+
+	log.Printf("Asset CSV loaded (%s): %d IP, %d MAC entries", path, len(loaded.byIP), len(loaded.byMAC))
+	return loaded
+}
+
+// loadAssetCSV başlık satırı zorunlu olan CSV'yi okur. Tanınan kolonlar:
+// ip, mac, hostname, owner, department (sıra önemli değil).
+func loadAssetCSV(path string) (*nasAssets, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header read error: %w", err)
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["ip"]; !ok {
+		if _, ok := cols["mac"]; !ok {
+			return nil, fmt.Errorf("CSV must have an ip or mac column")
+		}
+	}
+
+	get := func(rec []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	na := &nasAssets{
+		byIP:  make(map[string]assetInfo),
+		byMAC: make(map[string]assetInfo),
+	}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row read error: %w", err)
+		}
+
+		a := assetInfo{
+			Hostname:   get(rec, "hostname"),
+			Owner:      get(rec, "owner"),
+			Department: get(rec, "department"),
+		}
+		if ip := get(rec, "ip"); ip != "" {
+			na.byIP[ip] = a
+		}
+		if mac := get(rec, "mac"); mac != "" {
+			na.byMAC[normalizeMAC(mac)] = a
+		}
+	}
+	return na, nil
+}
+
+func normalizeMAC(mac string) string {
+	m := strings.ToLower(strings.TrimSpace(mac))
+	m = strings.NewReplacer("-", "", ":", "", ".", "").Replace(m)
+	return m
+}
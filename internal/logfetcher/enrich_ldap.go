@@ -0,0 +1,264 @@
+// internal/logfetcher/enrich_ldap.go
+
+package logfetcher
+
+import (
+	"container/list"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"tedalogger-logfetcher/config"
+)
+
+type directoryInfo struct {
+	DisplayName string
+	Department  string
+	Groups      []string
+}
+
+type ldapCacheEntry struct {
+	user    string
+	info    *directoryInfo
+	expires time.Time
+}
+
+// ldapMaxIdle havuzda bekletilecek en fazla bağlantıdır.
+const ldapMaxIdle = 4
+
+// ldapResolver kullanıcı adlarını dizin servisinde arar. Sorgu log işleme yolunda yapıldığından
+// sonuçlar (bulunamayanlar dahil) TTL süresince sınırlı bir LRU önbellekte tutulur; dizine
+// ulaşılamazsa LDAP_FAILURE_TTL boyunca hiç sorgu atılmaz ve belgeler beklemeden yazılır.
+// Bağlantılar küçük bir havuzdan alınır; ağ işlemi sırasında kilit tutulmaz.
+type ldapResolver struct {
+	url         string
+	bindDN      string
+	bindPass    string
+	baseDN      string
+	userFilter  string
+	startTLS    bool
+	timeout     time.Duration
+	failureTTL  time.Duration
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	connMu    sync.Mutex
+	idle      []*ldap.Conn
+	downUntil time.Time
+
+	cacheMu   sync.Mutex
+	cache     map[string]*list.Element
+	lru       *list.List // önde en son kullanılan
+	cacheSize int
+}
+
+func newLDAPResolver(cfg *config.Config) *ldapResolver {
+	return &ldapResolver{
+		url:         cfg.LDAPURL,
+		bindDN:      cfg.LDAPBindDN,
+		bindPass:    cfg.LDAPBindPass,
+		baseDN:      cfg.LDAPBaseDN,
+		userFilter:  cfg.LDAPUserFilter,
+		startTLS:    cfg.LDAPStartTLS,
+		timeout:     cfg.LDAPTimeout,
+		failureTTL:  cfg.LDAPFailureTTL,
+		ttl:         cfg.EnrichCacheTTL,
+		negativeTTL: cfg.EnrichNegCacheTTL,
+		cache:       make(map[string]*list.Element),
+		lru:         list.New(),
+		cacheSize:   cfg.EnrichCacheSize,
+	}
+}
+
+// resolve kullanıcının dizin bilgisini döndürür. Dizin erişilemez durumdayken sorgu atmadan
+// nil döner; hata yalnızca erişimin koptuğu sorguda bildirilir.
+func (r *ldapResolver) resolve(user string) (*directoryInfo, error) {
+	if user == "" {
+		return nil, nil
+	}
+	if info, ok := r.cached(user); ok {
+		return info, nil
+	}
+	if r.down() {
+		return nil, nil
+	}
+
+	info, err := r.search(user)
+	if err != nil {
+		r.markDown()
+		return nil, fmt.Errorf("%w; skipping lookups for %s", err, r.failureTTL)
+	}
+	r.store(user, info)
+	return info, nil
+}
+
+func (r *ldapResolver) cached(user string) (*directoryInfo, bool) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	el, ok := r.cache[user]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*ldapCacheEntry)
+	if time.Now().After(entry.expires) {
+		r.lru.Remove(el)
+		delete(r.cache, user)
+		return nil, false
+	}
+	r.lru.MoveToFront(el)
+	return entry.info, true
+}
+
+func (r *ldapResolver) store(user string, info *directoryInfo) {
+	ttl := r.ttl
+	if info == nil {
+		ttl = r.negativeTTL
+	}
+	entry := &ldapCacheEntry{user: user, info: info, expires: time.Now().Add(ttl)}
+
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	if el, ok := r.cache[user]; ok {
+		el.Value = entry
+		r.lru.MoveToFront(el)
+		return
+	}
+	r.cache[user] = r.lru.PushFront(entry)
+	for r.cacheSize > 0 && r.lru.Len() > r.cacheSize {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.cache, oldest.Value.(*ldapCacheEntry).user)
+	}
+}
+
+func (r *ldapResolver) down() bool {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+	return time.Now().Before(r.downUntil)
+}
+
+func (r *ldapResolver) markDown() {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+	r.downUntil = time.Now().Add(r.failureTTL)
+}
+
+// acquire havuzdan açık bir bağlantı alır; yoksa kilit dışında yenisini açar.
+func (r *ldapResolver) acquire() (*ldap.Conn, error) {
+	r.connMu.Lock()
+	for len(r.idle) > 0 {
+		conn := r.idle[len(r.idle)-1]
+		r.idle = r.idle[:len(r.idle)-1]
+		if !conn.IsClosing() {
+			r.connMu.Unlock()
+			return conn, nil
+		}
+	}
+	r.connMu.Unlock()
+	return r.dial()
+}
+
+func (r *ldapResolver) release(conn *ldap.Conn) {
+	r.connMu.Lock()
+	if len(r.idle) < ldapMaxIdle {
+		r.idle = append(r.idle, conn)
+		conn = nil
+	}
+	r.connMu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (r *ldapResolver) search(user string) (*directoryInfo, error) {
+	conn, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	timeLimit := int((r.timeout + time.Second - 1) / time.Second)
+	req := ldap.NewSearchRequest(
+		r.baseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,         // sizeLimit
+		timeLimit, // sn
+		false,
+		fmt.Sprintf(r.userFilter, ldap.EscapeFilter(user)),
+		[]string{"displayName", "cn", "department", "ou", "memberOf"},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		conn.Close()
+		return nil, fmt.Errorf("ldap search error: %w", err)
+	}
+	r.release(conn)
+	if res == nil || len(res.Entries) == 0 {
+		return nil, nil
+	}
+
+	e := res.Entries[0]
+	info := &directoryInfo{
+		DisplayName: firstNonEmpty(e.GetAttributeValue("displayName"), e.GetAttributeValue("cn")),
+		Department:  firstNonEmpty(e.GetAttributeValue("department"), e.GetAttributeValue("ou")),
+	}
+	for _, dn := range e.GetAttributeValues("memberOf") {
+		info.Groups = append(info.Groups, groupNameFromDN(dn))
+	}
+	return info, nil
+}
+
+func (r *ldapResolver) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(r.url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: r.timeout}),
+		ldap.DialWithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial error: %w", err)
+	}
+	conn.SetTimeout(r.timeout)
+
+	if r.startTLS {
+		host := strings.TrimPrefix(r.url, "ldap://")
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls error: %w", err)
+		}
+	}
+
+	if r.bindDN != "" {
+		if err := conn.Bind(r.bindDN, r.bindPass); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap bind error: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// groupNameFromDN "CN=VPN Users,OU=Groups,DC=corp,DC=local" değerinden "VPN Users" döndürür.
+func groupNameFromDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
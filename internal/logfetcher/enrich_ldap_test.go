@@ -0,0 +1,138 @@
+package logfetcher
+
+import (
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+func testLDAPResolver(url string) *ldapResolver {
+	return newLDAPResolver(&config.Config{
+		LDAPURL:           url,
+		LDAPBaseDN:        "dc=example,dc=org",
+		LDAPUserFilter:    "(uid=%s)",
+		LDAPTimeout:       200 * time.Millisecond,
+		LDAPFailureTTL:    time.Minute,
+		EnrichCacheTTL:    time.Minute,
+		EnrichNegCacheTTL: time.Minute,
+		EnrichCacheSize:   2,
+	})
+}
+
+func TestLDAPCacheEviction(t *testing.T) {
+	r := testLDAPResolver("")
+	r.store("a", &directoryInfo{DisplayName: "A"})
+	r.store("b", nil)
+	if _, ok := r.cached("a"); !ok { // a artık en son kullanılan
+		t.Fatal("a not cached")
+	}
+	r.store("c", &directoryInfo{DisplayName: "C"})
+
+	if _, ok := r.cached("b"); ok {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	if info, ok := r.cached("a"); !ok || info.DisplayName != "A" {
+		t.Fatalf("a = %v, %v", info, ok)
+	}
+	if info, ok := r.cached("c"); !ok || info.DisplayName != "C" {
+		t.Fatalf("c = %v, %v", info, ok)
+	}
+	if r.lru.Len() != 2 || len(r.cache) != 2 {
+		t.Fatalf("cache size = %d/%d, want 2", r.lru.Len(), len(r.cache))
+	}
+}
+
+func TestLDAPCacheExpiry(t *testing.T) {
+	r := testLDAPResolver("")
+	r.negativeTTL = -time.Second
+	r.store("gone", nil)
+	if _, ok := r.cached("gone"); ok {
+		t.Fatal("expired entry returned")
+	}
+	if len(r.cache) != 0 {
+		t.Fatal("expired entry not removed")
+	}
+}
+
+// TestLDAPUnavailable yanıt vermeyen dizinde sorgunun zaman aşımıyla bittiğini ve
+// LDAP_FAILURE_TTL boyunca yeniden bağlanılmadığını sınar.
+func TestLDAPUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			defer conn.Close() // hiç yanıt verme
+		}
+	}()
+
+	r := testLDAPResolver("ldap://" + ln.Addr().String())
+	start := time.Now()
+	if _, err := r.resolve("alice"); err == nil {
+		t.Fatal("resolve against a hung server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("resolve took %s", elapsed)
+	}
+
+	for _, user := range []string{"alice", "bob"} {
+		info, err := r.resolve(user)
+		if info != nil || err != nil {
+			t.Fatalf("resolve(%s) while down = %v, %v", user, info, err)
+		}
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Fatalf("dialed %d times, want 1", n)
+	}
+}
+
+// TestLDAPOpenLDAP yerel bir OpenLDAP'a karşı çalışır, örn.:
+//
+//	docker run -d -p 1389:1389 -e LDAP_ADMIN_PASSWORD=admin -e LDAP_USERS=alice \
+//	  -e LDAP_PASSWORDS=secret bitnami/openldap
+//	LDAP_TEST_URL=ldap://127.0.0.1:1389 LDAP_TEST_BASE_DN=dc=example,dc=org \
+//	  LDAP_TEST_BIND_DN=cn=admin,dc=example,dc=org LDAP_TEST_BIND_PASS=admin \
+//	  LDAP_TEST_USER=alice go test ./internal/logfetcher -run OpenLDAP
+func TestLDAPOpenLDAP(t *testing.T) {
+	url := os.Getenv("LDAP_TEST_URL")
+	if url == "" {
+		t.Skip("LDAP_TEST_URL not set")
+	}
+	r := testLDAPResolver(url)
+	r.baseDN = os.Getenv("LDAP_TEST_BASE_DN")
+	r.bindDN = os.Getenv("LDAP_TEST_BIND_DN")
+	r.bindPass = os.Getenv("LDAP_TEST_BIND_PASS")
+	r.userFilter = "(|(uid=%[1]s)(sAMAccountName=%[1]s))"
+	r.timeout = 5 * time.Second
+
+	info, err := r.resolve(os.Getenv("LDAP_TEST_USER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.DisplayName == "" {
+		t.Fatalf("user not resolved: %+v", info)
+	}
+
+	missing, err := r.resolve("no-such-user-tedalogger")
+	if err != nil || missing != nil {
+		t.Fatalf("missing user = %+v, %v", missing, err)
+	}
+	if _, ok := r.cached("no-such-user-tedalogger"); !ok {
+		t.Fatal("missing user not cached")
+	}
+	if len(r.idle) != 1 {
+		t.Fatalf("idle connections = %d, want 1", len(r.idle))
+	}
+}
@@ -0,0 +1,366 @@
+// internal/logfetcher/file_tail.go
+
+package logfetcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+// tailPosition bir dosyanın işlenmiş son tam satırının sonudur. Dosyalar inode ile
+// tanınır; böylece logrotate ile yeniden adlandırılan dosya kaldığı yerden okunmaya devam
+// eder ve aynı yolda açılan yeni dosya baştan okunur.
+type tailPosition struct {
+	Path   string `json:"path"`
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+type tailedFile struct {
+	path   string
+	nas    string
+	inode  uint64
+	f      *os.File
+	offset int64
+}
+
+// fileTailSource FILE_TAIL_PATHS'teki glob'lara uyan dosyaları izler. Konum yalnızca satır
+// ack/nack edildikten sonra ilerler ve dosyaya yazılır.
+type fileTailSource struct {
+	patterns map[string]string
+	posFile  string
+	poll     time.Duration
+	start    string
+	maxLine  int // SYSLOG_MAX_MESSAGE; uzun satırın fazlası atılır
+
+	files map[uint64]*tailedFile
+	saved map[uint64]tailPosition
+	dirty bool
+}
+
+// StartFileTail FILE_TAIL_PATHS ayarlıysa dosyaları izlemeye başlar.
+func StartFileTail() {
+	cfg := config.GetConfig()
+	if len(cfg.FileTailPaths) == 0 {
+		return
+	}
+
+	// NAS listesi gelmeden okunan satırlar bilinmeyen kaynak diye atlanıp konum ilerlerdi.
+	for !nasRegistryLoaded() {
+		time.Sleep(time.Second)
+	}
+	proc := newNASProcessor(getSinkRouter(), "file", "[Tail]", -1)
+
+	src := &fileTailSource{
+		patterns: cfg.FileTailPaths,
+		posFile:  cfg.FileTailPositions,
+		poll:     cfg.FileTailPoll,
+		start:    cfg.FileTailStart,
+		maxLine:  cfg.SyslogMaxMessage,
+	}
+	ctx := context.Background()
+	if err := src.Run(ctx, func(m SourceMessage) { proc.process(ctx, m) }); err != nil {
+		log.Printf("[Tail] %v", err)
+	}
+}
+
+func (t *fileTailSource) Name() string { return "file" }
+
+func (t *fileTailSource) Run(ctx context.Context, handle func(SourceMessage)) error {
+	if t.start != "beginning" && t.start != "end" {
+		return fmt.Errorf("invalid FILE_TAIL_START %q", t.start)
+	}
+
+	saved, err := loadTailPositions(t.posFile)
+	if err != nil {
+		return err
+	}
+	// Konum dosyası hiç yoksa ilk taramada bulunan dosyalar FILE_TAIL_START'a göre açılır;
+	// sonradan ortaya çıkan dosyalar her zaman baştan okunur.
+	firstRun := saved == nil
+	t.saved = saved
+	t.files = make(map[uint64]*tailedFile)
+
+	defer func() {
+		t.save()
+		for _, tf := range t.files {
+			tf.f.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(t.poll)
+	defer ticker.Stop()
+
+	for {
+		t.scan(firstRun)
+		firstRun = false
+		for ino, tf := range t.files {
+			if err := t.readLines(ctx, tf, handle); err != nil {
+				log.Printf("[Tail] Read error %s: %v", tf.path, err)
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if tf.path == "" {
+				// Dosya artık hiçbir glob'a uymuyor (silindi ya da döndürülüp taşındı) ve sonuna
+				// kadar okundu.
+				tf.f.Close()
+				delete(t.files, ino)
+				t.dirty = true
+			}
+		}
+		t.save()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan glob'lara uyan dosyaları açar. Artık uymayan açık dosyaların yolu boşaltılır;
+// Run onları sonuna kadar okuyup kapatır.
+func (t *fileTailSource) scan(firstRun bool) {
+	type match struct{ path, nas string }
+	matched := make(map[uint64]match)
+	for pattern, nas := range t.patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Printf("[Tail] Invalid pattern %q: %v", pattern, err)
+			continue
+		}
+		for _, p := range paths {
+			fi, err := os.Stat(p)
+			if err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			matched[fileInode(fi)] = match{path: p, nas: nas}
+		}
+	}
+
+	for ino, tf := range t.files {
+		if m, ok := matched[ino]; ok {
+			tf.path = m.path
+		} else {
+			tf.path = ""
+		}
+	}
+
+	for ino, m := range matched {
+		if _, ok := t.files[ino]; ok {
+			continue
+		}
+		f, err := os.Open(m.path)
+		if err != nil {
+			log.Printf("[Tail] Open error %s: %v", m.path, err)
+			continue
+		}
+		fi, err := f.Stat()
+		if err != nil || fileInode(fi) != ino {
+			// Stat ile Open arasında döndürüldü; bir sonraki taramada yeniden denenir.
+			f.Close()
+			continue
+		}
+
+		var offset int64
+		if pos, ok := t.saved[ino]; ok && pos.Offset <= fi.Size() {
+			offset = pos.Offset
+		} else if firstRun && t.start == "end" {
+			offset = fi.Size()
+		}
+		t.files[ino] = &tailedFile{path: m.path, nas: m.nas, inode: ino, f: f, offset: offset}
+		log.Printf("[Tail] Following %s (nas=%s) from offset %d", m.path, m.nas, offset)
+	}
+}
+
+// tailBatchLines bir seferde kuyruğa verilip sonucu beklenen en fazla satır sayısıdır.
+const tailBatchLines = 10000
+
+// readLines dosyada biriken tam satırları işler. Satır sonu gelmemiş son satır bir sonraki
+// turda okunur. Satırlar toplu olarak kuyruğa verilir; konum, baştan itibaren sonuçlanmış
+// satırların sonuna ilerletilir.
+func (t *fileTailSource) readLines(ctx context.Context, tf *tailedFile, handle func(SourceMessage)) error {
+	fi, err := tf.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < tf.offset {
+		// copytruncate ile kesildi.
+		log.Printf("[Tail] %s truncated, reading from start", tf.path)
+		tf.offset = 0
+		t.dirty = true
+	}
+	if fi.Size() == tf.offset {
+		return nil
+	}
+	if _, err := tf.f.Seek(tf.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReaderSize(tf.f, 64*1024)
+	for ctx.Err() == nil {
+		n, err := t.readBatch(ctx, tf, r, handle)
+		if err != nil || n < tailBatchLines {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *fileTailSource) readBatch(ctx context.Context, tf *tailedFile, r *bufio.Reader, handle func(SourceMessage)) (int, error) {
+	type pendingLine struct {
+		end  int64
+		done atomic.Bool
+	}
+	var (
+		batch   []*pendingLine
+		wg      sync.WaitGroup
+		readErr error
+	)
+
+	offset := tf.offset
+	for len(batch) < tailBatchLines {
+		line, n, truncated, err := readBoundedLine(r, t.maxLine)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		if truncated {
+			incCounter("file_truncated", 1)
+		}
+
+		start := offset
+		offset += n
+		pl := &pendingLine{end: offset}
+		batch = append(batch, pl)
+
+		body := bytes.TrimRight(line, "\r")
+		if len(body) == 0 {
+			pl.done.Store(true)
+			continue
+		}
+		wg.Add(1)
+		t.emit(tf, body, start, handle, func() {
+			pl.done.Store(true)
+			wg.Done()
+		})
+	}
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+	select {
+	case <-allDone:
+	case <-ctx.Done():
+	}
+
+	for _, pl := range batch {
+		if !pl.done.Load() {
+			break
+		}
+		tf.offset = pl.end
+		t.dirty = true
+	}
+	return len(batch), readErr
+}
+
+func (t *fileTailSource) emit(tf *tailedFile, body []byte, at int64, handle func(SourceMessage), finished func()) {
+	incCounter("file_lines", 1)
+	handle(SourceMessage{
+		Body: body,
+		Meta: LogMessage{
+			FromHost:     tf.nas,
+			TimeReported: time.Now().UTC().Format(time.RFC3339),
+		},
+		NAS: tf.nas,
+		ID:  fmt.Sprintf("file:%d:%d", tf.inode, at),
+		Ack: finished,
+		Nack: func(err error, retryable bool) {
+			defer finished()
+			if errors.Is(err, errUnparseable) {
+				return
+			}
+			incCounter("file_dropped", 1)
+			log.Printf("ES index error, dropping line from %s@%d: %v", tf.path, at, err)
+		},
+	})
+}
+
+func (t *fileTailSource) save() {
+	if !t.dirty {
+		return
+	}
+	positions := make([]tailPosition, 0, len(t.files))
+	t.saved = make(map[uint64]tailPosition, len(t.files))
+	for ino, tf := range t.files {
+		pos := tailPosition{Path: tf.path, Inode: ino, Offset: tf.offset}
+		positions = append(positions, pos)
+		t.saved[ino] = pos
+	}
+	if err := writeTailPositions(t.posFile, positions); err != nil {
+		log.Printf("[Tail] Position save error: %v", err)
+		return
+	}
+	t.dirty = false
+}
+
+// loadTailPositions dosya yoksa nil map döndürür.
+func loadTailPositions(path string) (map[uint64]tailPosition, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tail positions read error: %w", err)
+	}
+
+	var list []tailPosition
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("tail positions parse error: %w", err)
+	}
+	out := make(map[uint64]tailPosition, len(list))
+	for _, pos := range list {
+		out[pos.Inode] = pos
+	}
+	return out, nil
+}
+
+// writeTailPositions yarım yazılmış konum dosyası kalmaması için geçici dosya + rename kullanır.
+func writeTailPositions(path string, positions []tailPosition) error {
+	data, err := json.MarshalIndent(positions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func fileInode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,263 @@
+// internal/logfetcher/kafka_source.go
+
+package logfetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"tedalogger-logfetcher/config"
+)
+
+// kafkaSource consumer group ile topic'leri okur. Her partition'ın offset'i, o partition'daki
+// kayıtlar işlendikten sonra commit edilir; indekslenemeyen kayıt (geçici ES hatası)
+// yerinde yeniden denenir.
+type kafkaSource struct {
+	cl *kgo.Client
+
+	nasFrom  string
+	prefix   string
+	topicNAS map[string]string
+}
+
+// StartKafkaConsumer KAFKA_BROKERS ayarlıysa consumer group'a katılır ve kapanana kadar
+// tüketir. Ayarlı değilse hiçbir şey yapmaz.
+func StartKafkaConsumer() {
+	cfg := config.GetConfig()
+	if len(cfg.KafkaBrokers) == 0 {
+		return
+	}
+	if len(cfg.KafkaTopics) == 0 {
+		log.Printf("[Kafka] KAFKA_BROKERS set but KAFKA_TOPICS is empty; Kafka source disabled")
+		return
+	}
+
+	opts, err := kafkaOptions(cfg)
+	if err != nil {
+		log.Printf("[Kafka] Config error: %v", err)
+		return
+	}
+
+	// NAS listesi gelmeden tüketilen kayıtlar bilinmeyen kaynak diye atılıp commit edilirdi.
+	for !nasRegistryLoaded() {
+		time.Sleep(time.Second)
+	}
+	proc := newNASProcessor(getSinkRouter(), "kafka", "[Kafka]", -1)
+
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		log.Printf("[Kafka] Client error: %v", err)
+		return
+	}
+	defer cl.Close()
+
+	src := &kafkaSource{
+		cl:       cl,
+		nasFrom:  cfg.KafkaNASFrom,
+		prefix:   cfg.KafkaTopicPrefix,
+		topicNAS: cfg.KafkaTopicNAS,
+	}
+	log.Printf("[Kafka] Consuming %v as group %s (NAS from %s)", cfg.KafkaTopics, cfg.KafkaGroup, src.nasFrom)
+
+	ctx := context.Background()
+	if err := src.Run(ctx, func(m SourceMessage) { proc.process(ctx, m) }); err != nil {
+		log.Printf("[Kafka] %v", err)
+	}
+}
+
+func kafkaOptions(cfg *config.Config) ([]kgo.Opt, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.KafkaBrokers...),
+		kgo.ClientID(cfg.KafkaClientID),
+		kgo.ConsumerGroup(cfg.KafkaGroup),
+		kgo.ConsumeTopics(cfg.KafkaTopics...),
+		kgo.DisableAutoCommit(),
+		// Poll edilen kayıtlar işlenip commit edilmeden partition başka üyeye geçmesin.
+		kgo.BlockRebalanceOnPoll(),
+	}
+	if cfg.KafkaTopicsRegex {
+		opts = append(opts, kgo.ConsumeRegex())
+	}
+
+	switch cfg.KafkaStart {
+	case "earliest":
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	case "latest":
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+	default:
+		return nil, fmt.Errorf("invalid KAFKA_START %q", cfg.KafkaStart)
+	}
+
+	switch cfg.KafkaNASFrom {
+	case "topic", "key":
+	default:
+		return nil, fmt.Errorf("invalid KAFKA_NAS_FROM %q", cfg.KafkaNASFrom)
+	}
+
+	if cfg.KafkaSASLMechanism != "" {
+		mech, err := kafkaSASL(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mech))
+	}
+
+	tc, err := kafkaTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tc != nil {
+		opts = append(opts, kgo.DialTLSConfig(tc))
+	}
+	return opts, nil
+}
+
+func kafkaSASL(cfg *config.Config) (sasl.Mechanism, error) {
+	switch strings.ToUpper(cfg.KafkaSASLMechanism) {
+	case "PLAIN":
+		return plain.Auth{User: cfg.KafkaSASLUser, Pass: cfg.KafkaSASLPass}.AsMechanism(), nil
+	case "SCRAM-SHA-256":
+		return scram.Auth{User: cfg.KafkaSASLUser, Pass: cfg.KafkaSASLPass}.AsSha256Mechanism(), nil
+	case "SCRAM-SHA-512":
+		return scram.Auth{User: cfg.KafkaSASLUser, Pass: cfg.KafkaSASLPass}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unsupported KAFKA_SASL_MECHANISM %q", cfg.KafkaSASLMechanism)
+	}
+}
+
+func kafkaTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.KafkaTLS {
+		return nil, nil
+	}
+
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.KafkaCAFile != "" {
+		pem, err := os.ReadFile(cfg.KafkaCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Kafka CA read error: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Kafka CA file has no valid certificates: %s", cfg.KafkaCAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.KafkaCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.KafkaCertFile, cfg.KafkaKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Kafka client certificate error: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+func (k *kafkaSource) Name() string { return "kafka" }
+
+// Run her poll'da partition'ları paralel işler; aynı partition içindeki sıra korunur.
+func (k *kafkaSource) Run(ctx context.Context, handle func(SourceMessage)) error {
+	for {
+		fetches := k.cl.PollFetches(ctx)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			return nil
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			incCounter("kafka_fetch_errors", 1)
+			log.Printf("[Kafka] Fetch error %s/%d: %v", topic, partition, err)
+		})
+
+		var wg sync.WaitGroup
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			if len(p.Records) == 0 {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				k.processPartition(ctx, p, handle)
+			}()
+		})
+		wg.Wait()
+		k.cl.AllowRebalance()
+	}
+}
+
+// processPartition poll edilen kayıtları kuyruğa verir ve partition offset'ini ancak hepsi
+// sonuçlandıktan sonra commit eder.
+func (k *kafkaSource) processPartition(ctx context.Context, p kgo.FetchTopicPartition, handle func(SourceMessage)) {
+	var wg sync.WaitGroup
+	for _, rec := range p.Records {
+		incCounter("kafka_received", 1)
+		wg.Add(1)
+		handle(k.message(rec, wg.Done))
+	}
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+	select {
+	case <-allDone:
+	case <-ctx.Done():
+		// Kayıtlar sonuçlanmadan kapanıyoruz; offset ilerletilmez.
+		return
+	}
+
+	last := p.Records[len(p.Records)-1]
+	if err := k.cl.CommitRecords(ctx, last); err != nil {
+		// Commit edilemeyen kayıtlar yeniden teslim edilir; at-least-once. ES_DETERMINISTIC_IDS
+		// kapalıysa bunlar ikinci kez yazılır.
+		incCounter("kafka_commit_errors", 1)
+		log.Printf("[Kafka] Commit error %s/%d@%d: %v", p.Topic, p.Partition, last.Offset, err)
+	}
+}
+
+// message kaydı SourceMessage'a çevirir. Commit partition bazında yapıldığından Ack yalnızca
+// finished'ı çağırır; Nack kalıcı hatada çağrılır ve kayıt atlanır.
+func (k *kafkaSource) message(rec *kgo.Record, finished func()) SourceMessage {
+	nas := k.nasFor(rec)
+	return SourceMessage{
+		Body: rec.Value,
+		Meta: LogMessage{
+			FromHost:     nas,
+			TimeReported: rec.Timestamp.UTC().Format(time.RFC3339),
+		},
+		NAS: nas,
+		ID:  fmt.Sprintf("kafka:%s:%d:%d", rec.Topic, rec.Partition, rec.Offset),
+		Ack: finished,
+		Nack: func(err error, retryable bool) {
+			defer finished()
+			if errors.Is(err, errUnparseable) {
+				return
+			}
+			incCounter("kafka_dropped", 1)
+			log.Printf("ES index error, dropping Kafka record %s/%d@%d: %v",
+				rec.Topic, rec.Partition, rec.Offset, err)
+		},
+	}
+}
+
+// nasFor kaydın NAS adını KAFKA_NAS_FROM'a göre bulur: key modunda kayıt anahtarı, topic
+// modunda KAFKA_TOPIC_NAS eşlemesi ya da öneki atılmış topic adı.
+func (k *kafkaSource) nasFor(rec *kgo.Record) string {
+	if k.nasFrom == "key" {
+		return string(rec.Key)
+	}
+	if nas, ok := k.topicNAS[rec.Topic]; ok {
+		return nas
+	}
+	return strings.TrimPrefix(rec.Topic, k.prefix)
+}
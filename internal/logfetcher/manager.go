@@ -9,25 +9,37 @@ import (
 	"strings"
 	"sync"
 	"time"
-)
 
-type consumerHandle struct {
-	cancelFunc context.CancelFunc
-	queueName  string
-}
+	"tedalogger-logfetcher/config"
+)
 
 func StartManager() {
 	consumers := make(map[string]*consumerHandle)
 	var mu sync.Mutex
+	declareQueues := config.GetConfig().RabbitMQDeclareQueues
+
+	go reportCounters(time.Minute)
 
 	go func() {
+		policyApplied := !declareQueues
 		for {
+			if !policyApplied {
+				if err := applyNASQueuePolicy(config.GetConfig()); err != nil {
+					log.Printf("[Topology] Policy error: %v", err)
+				} else {
+					policyApplied = true
+				}
+			}
+
 			nasList, err := fetchNASList()
 			if err != nil {
 				log.Printf("Error fetching NAS list: %v", err)
 				time.Sleep(10 * time.Second)
 				continue
 			}
+			// Yeni NAS'ın günlük indeks deseni belgeleri gelmeden şablonlara eklenir.
+			refreshNASTemplates(nasList)
+			updateNASRegistry(nasList)
 
 			desiredQueues := make(map[string]bool)
 			for _, nas := range nasList {
@@ -44,6 +56,27 @@ func StartManager() {
 					log.Printf("Stopping consumer for removed queue: %s", q)
 					handle.cancelFunc()
 					delete(consumers, q)
+
+					if declareQueues {
+						go func(h *consumerHandle) {
+							<-h.done
+							brand, nasIP := parseQueueName(h.queueName)
+							if err := retireNASTopology(h.queueName, brand, nasIP); err != nil {
+								log.Printf("[Topology] Retire error (%s): %v", h.queueName, err)
+							}
+						}(handle)
+					}
+					continue
+				}
+
+				state, lastErr := handle.status()
+				if handle.exited() {
+					log.Printf("Consumer %s exited (state=%s, err=%v), restarting", q, state, lastErr)
+					incCounter("consumer_restarts", 1)
+					handle.cancelFunc()
+					delete(consumers, q)
+				} else if state != stateRunning {
+					log.Printf("Consumer %s is %s (last error: %v)", q, state, lastErr)
 				}
 			}
 
@@ -51,19 +84,18 @@ func StartManager() {
 				if _, exists := consumers[q]; !exists {
 					log.Printf("Starting consumer for new queue: %s", q)
 
-					ctx, cancel := context.WithCancel(context.Background())
-					ch := &consumerHandle{
-						cancelFunc: cancel,
-						queueName:  q,
+					brand, nasIP := parseQueueName(q)
+					if declareQueues {
+						if err := declareNASTopology(q, brand, nasIP); err != nil {
+							log.Printf("[Topology] Declare error (%s): %v", q, err)
+						}
 					}
-					consumers[q] = ch
 
-					go func(qName string) {
-						brand, nasIP := parseQueueName(qName)
-						if err := startConsumer(ctx, qName, brand, nasIP); err != nil {
-							log.Printf("Consumer %s error: %v", qName, err)
-						}
-					}(q)
+					ctx, cancel := context.WithCancel(context.Background())
+					handle := newConsumerHandle(q, cancel)
+					consumers[q] = handle
+
+					go superviseConsumer(ctx, handle, brand, nasIP)
 				}
 			}
 			mu.Unlock()
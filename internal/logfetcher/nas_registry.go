@@ -0,0 +1,39 @@
+// internal/logfetcher/nas_registry.go
+
+package logfetcher
+
+import "sync"
+
+// nasRegistry manager'ın /nas/get_all ile çektiği son listeyi tutar. Kuyruk adı taşımayan
+// kaynaklar (syslog) NAS'ı kaynak adresinden bulmak için kullanır.
+var (
+	nasRegistryMu sync.RWMutex
+	nasByAddr     = make(map[string]NAS)
+	nasLoaded     bool
+)
+
+func updateNASRegistry(list []NAS) {
+	m := make(map[string]NAS, len(list))
+	for _, n := range list {
+		m[n.Nasname] = n
+	}
+	nasRegistryMu.Lock()
+	nasByAddr = m
+	nasLoaded = true
+	nasRegistryMu.Unlock()
+}
+
+func lookupNAS(addr string) (NAS, bool) {
+	nasRegistryMu.RLock()
+	defer nasRegistryMu.RUnlock()
+	n, ok := nasByAddr[addr]
+	return n, ok
+}
+
+// nasRegistryLoaded manager NAS listesini en az bir kez çektiyse true döner. Commit'li
+// kaynaklar (Kafka) liste gelmeden mesajları bilinmeyen NAS diye atmamak için bekler.
+func nasRegistryLoaded() bool {
+	nasRegistryMu.RLock()
+	defer nasRegistryMu.RUnlock()
+	return nasLoaded
+}
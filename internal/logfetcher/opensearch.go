@@ -0,0 +1,330 @@
+// internal/logfetcher/opensearch.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	sinkElasticsearch = "elasticsearch"
+	sinkOpenSearch    = "opensearch"
+)
+
+// openSearchClient OpenSearch kümesine bağlantıdır. go-elasticsearch istemcisi her yanıtta
+// X-Elastic-Product başlığını aradığı için OpenSearch'le konuşmaz; esapi katmanı ise yalnızca
+// REST isteklerini kurar. Bu yüzden ürün kontrolü yapmayan transport üzerinde aynı esapi
+// çağrıları (bulk, get, index, şablonlar) kullanılır. ISM gibi esapi'de olmayan uçlar perform
+// ile çağrılır.
+type openSearchClient struct {
+	*esapi.API
+	transport elastictransport.Interface
+	version   string
+}
+
+func connectOpenSearch() (*openSearchClient, error) {
+	cfg := config.GetConfig()
+	if len(cfg.OpenSearchURLs) == 0 {
+		return nil, fmt.Errorf("OPENSEARCH_URL is required")
+	}
+
+	urls := make([]*url.URL, 0, len(cfg.OpenSearchURLs))
+	for _, raw := range cfg.OpenSearchURLs {
+		u, err := url.Parse(strings.TrimRight(raw, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPENSEARCH_URL %q: %w", raw, err)
+		}
+		urls = append(urls, u)
+	}
+
+	retryOn, err := retryStatuses(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := clusterTLSConfig("OpenSearch", "OPENSEARCH_CA_FINGERPRINT",
+		cfg.OpenSearchCAFile, cfg.OpenSearchFingerprint, cfg.OpenSearchCertFile, cfg.OpenSearchKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tp, err := elastictransport.New(elastictransport.Config{
+		URLs:                urls,
+		Username:            cfg.OpenSearchUser,
+		Password:            cfg.OpenSearchPass,
+		CompressRequestBody: cfg.ElasticCompress,
+		MaxRetries:          cfg.ElasticMaxRetries,
+		DisableRetry:        cfg.ElasticMaxRetries <= 0,
+		RetryOnStatus:       retryOn,
+		RetryBackoff:        retryBackoff(cfg),
+		Transport:           clusterTransport(cfg, tc),
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := &openSearchClient{API: esapi.New(tp), transport: tp}
+
+	res, err := c.Info()
+	if err != nil {
+		return nil, fmt.Errorf("opensearch info error: %w", err)
+	}
+	data, err := readBody(res)
+	if err != nil {
+		return nil, fmt.Errorf("opensearch info error: %w", err)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("opensearch error response: %s %s", res.Status(), data)
+	}
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+			Number       string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("opensearch info parse error: %w", err)
+	}
+	if info.Version.Distribution != sinkOpenSearch {
+		return nil, fmt.Errorf("OPENSEARCH_URL is not an OpenSearch cluster (version %s)", info.Version.Number)
+	}
+	c.version = info.Version.Number
+
+	auth := "none"
+	if cfg.OpenSearchUser != "" {
+		auth = "basic"
+	}
+	log.Printf("Connected to OpenSearch %s at %s (Auth: %s)", c.version, strings.Join(cfg.OpenSearchURLs, ","), auth)
+	return c, nil
+}
+
+func connectOpenSearchWithRetry(tag string) *openSearchClient {
+	for attempt := 0; ; attempt++ {
+		c, err := connectOpenSearch()
+		if err == nil {
+			return c
+		}
+		delay := backoffDelay(attempt)
+		log.Printf("%s OpenSearch connection error: %v; retrying in %s", tag, err, delay)
+		time.Sleep(delay)
+	}
+}
+
+// connectCluster stream offset'lerinin tutulduğu kümeye bağlanır. Yalnızca ortak esapi
+// uçlarını kullanan araçlar (streamctl) içindir.
+func connectCluster() (*esapi.API, error) {
+	if offsetSinkName(config.GetConfig()) == sinkOpenSearch {
+		c, err := connectOpenSearch()
+		if err != nil {
+			return nil, err
+		}
+		return c.API, nil
+	}
+	es, err := connectES()
+	if err != nil {
+		return nil, err
+	}
+	return es.API, nil
+}
+
+// perform esapi'de karşılığı olmayan bir isteği gönderir.
+func (c *openSearchClient) perform(method, path string, query url.Values, body []byte) (*esapi.Response, error) {
+	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(query) > 0 {
+		req.URL.RawQuery = query.Encode()
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.transport.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+	return &esapi.Response{StatusCode: res.StatusCode, Header: res.Header, Body: res.Body}, nil
+}
+
+// supportsWildcard wildcard alan türü OpenSearch 2.15 ile geldi.
+func (c *openSearchClient) supportsWildcard() bool {
+	parts := strings.SplitN(c.version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return major > 2 || (major == 2 && minor >= 15)
+}
+
+// ISM politikaları ism_template desenleriyle yeni indekslere bağlanır; bir indekse birden
+// çok şablon uyarsa önceliği yüksek olan seçilir.
+const (
+	ismPriorityDaily    = 100
+	ismPriorityRollover = 200
+)
+
+// InstallOpenSearchTemplates ILM yerine ISM politikalarını, ardından ES ile aynı component ve
+// index template'leri kurar. ISM politikası güncellendiğinde yalnızca yeni indeksler yeni
+// sürümü alır; mevcut indeksler eski politikayla devam eder.
+func InstallOpenSearchTemplates(c *openSearchClient) error {
+	cfg := config.GetConfig()
+	daily, err := ownedDailyPatterns(c.API, cfg)
+	if err != nil {
+		return err
+	}
+
+	var managed []string
+	for _, t := range indexTemplates(cfg, daily) {
+		if !t.dataStream {
+			managed = append(managed, t.patterns...)
+		}
+	}
+	if err := installManaged("ISM policy", cfg.ESILMPolicy,
+		ismPolicyBody(cfg, false, managed, ismPriorityDaily), ismPolicyAPI(c)); err != nil {
+		return err
+	}
+	if err := installManaged("ISM policy", rolloverPolicyName(cfg),
+		ismPolicyBody(cfg, true, []string{cfg.ESDataStreamPrefix + "*"}, ismPriorityRollover), ismPolicyAPI(c)); err != nil {
+		return err
+	}
+	return installIndexTemplates(c.API, cfg, daily, templateFlavor{wildcard: c.supportsWildcard()})
+}
+
+// ismPolicyBody ilmPolicyBody'nin ISM karşılığıdır: fazlar durumlara, min_age geçiş
+// koşullarına dönüşür. Rollover'lı politikada yaş rollover anından sayılır.
+func ismPolicyBody(cfg *config.Config, rollover bool, patterns []string, priority int) map[string]any {
+	ageCondition := "min_index_age"
+	if rollover {
+		ageCondition = "min_rollover_age"
+	}
+
+	hotActions := []any{map[string]any{"index_priority": map[string]any{"priority": 100}}}
+	if rollover {
+		r := map[string]any{}
+		if cfg.ESRolloverMaxSize != "" {
+			r["min_primary_shard_size"] = cfg.ESRolloverMaxSize
+		}
+		if cfg.ESRolloverMaxAge != "" {
+			r["min_index_age"] = cfg.ESRolloverMaxAge
+		}
+		if len(r) > 0 {
+			hotActions = append(hotActions, map[string]any{"rollover": r})
+		}
+	}
+
+	type stage struct {
+		name    string
+		after   string
+		actions []any
+	}
+	stages := []stage{{name: "hot", actions: hotActions}}
+	if cfg.ESILMWarmAfter != "" {
+		stages = append(stages, stage{"warm", cfg.ESILMWarmAfter, []any{
+			map[string]any{"index_priority": map[string]any{"priority": 50}},
+			map[string]any{"force_merge": map[string]any{"max_num_segments": 1}},
+			map[string]any{"read_only": map[string]any{}},
+		}})
+	}
+	if cfg.ESILMDeleteAfter != "" {
+		stages = append(stages, stage{"delete", cfg.ESILMDeleteAfter, []any{
+			map[string]any{"delete": map[string]any{}},
+		}})
+	}
+
+	states := make([]any, len(stages))
+	for i, st := range stages {
+		transitions := []any{}
+		if i+1 < len(stages) {
+			next := stages[i+1]
+			transitions = append(transitions, map[string]any{
+				"state_name": next.name,
+				"conditions": map[string]any{ageCondition: next.after},
+			})
+		}
+		states[i] = map[string]any{"name": st.name, "actions": st.actions, "transitions": transitions}
+	}
+
+	policy := map[string]any{
+		"default_state": "hot",
+		"states":        states,
+	}
+	if len(patterns) > 0 {
+		policy["ism_template"] = []any{map[string]any{
+			"index_patterns": patterns,
+			"priority":       priority,
+		}}
+	}
+	return map[string]any{"policy": policy}
+}
+
+// ismPolicyAPI ISM politikasının okuma/yazma uçlarıdır. ISM politikası _meta alanı kabul
+// etmediği için yönetim bilgisi açıklamada JSON olarak tutulur. Var olan politika yalnızca
+// okunduğu andaki seq_no/primary_term ile güncellenebilir.
+func ismPolicyAPI(c *openSearchClient) managedAPI {
+	var seqNo, primaryTerm *int
+	return managedAPI{
+		get: func(name string) (*esapi.Response, error) {
+			seqNo, primaryTerm = nil, nil
+			return c.perform(http.MethodGet, "/_plugins/_ism/policies/"+url.PathEscape(name), nil, nil)
+		},
+		put: func(name string, body []byte) (*esapi.Response, error) {
+			var doc struct {
+				Policy map[string]any `json:"policy"`
+			}
+			if err := json.Unmarshal(body, &doc); err != nil {
+				return nil, err
+			}
+			meta, err := json.Marshal(doc.Policy["_meta"])
+			if err != nil {
+				return nil, err
+			}
+			delete(doc.Policy, "_meta")
+			doc.Policy["description"] = string(meta)
+			body, err = json.Marshal(doc)
+			if err != nil {
+				return nil, err
+			}
+
+			query := url.Values{}
+			if seqNo != nil && primaryTerm != nil {
+				query.Set("if_seq_no", strconv.Itoa(*seqNo))
+				query.Set("if_primary_term", strconv.Itoa(*primaryTerm))
+			}
+			return c.perform(http.MethodPut, "/_plugins/_ism/policies/"+url.PathEscape(name), query, body)
+		},
+		meta: func(data []byte) (*managedMeta, error) {
+			var r struct {
+				SeqNo       *int `json:"_seq_no"`
+				PrimaryTerm *int `json:"_primary_term"`
+				Policy      struct {
+					Description string `json:"description"`
+				} `json:"policy"`
+			}
+			if err := json.Unmarshal(data, &r); err != nil {
+				return nil, err
+			}
+			seqNo, primaryTerm = r.SeqNo, r.PrimaryTerm
+
+			var meta managedMeta
+			if err := json.Unmarshal([]byte(r.Policy.Description), &meta); err != nil {
+				// Elle yazılmış açıklama: politika bizim değil, üzerine yazılır.
+				return nil, nil
+			}
+			return &meta, nil
+		},
+	}
+}
@@ -0,0 +1,14 @@
+package logfetcher
+
+import (
+	"testing"
+
+	"tedalogger-logfetcher/config"
+)
+
+func TestISMPolicyWithoutPatterns(t *testing.T) {
+	policy := ismPolicyBody(&config.Config{}, false, nil, ismPriorityDaily)["policy"].(map[string]any)
+	if _, ok := policy["ism_template"]; ok {
+		t.Fatal("ism_template set without index patterns")
+	}
+}
@@ -1,6 +1,7 @@
 package logfetcher
 
 import (
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,27 +13,84 @@ var (
 	fortiKV  = regexp.MustCompile(`(\w+)=(?:"([^"]+)"|([^",\s]+))`)
 )
 
-func parseAndDetermineBrand(lm LogMessage) ParsedLog {
-	lowerMsg := strings.ToLower(lm.Message)
+// parseWithDeclaredBrand NAS için tanımlı markanın parser'ını önceliklendirir. Bu parser
+// mesajdan URL çıkaramazsa içerikten tespit edilen markaya düşülür. Tanımlı marka ile
+// içerik uyuşmazsa belge "brand_mismatch" etiketiyle işaretlenir.
+func parseWithDeclaredBrand(lm LogMessage, declared string) ParsedLog {
+	declared = normalizeBrand(declared)
+	detected := detectBrand(lm.Message)
+
+	var pl ParsedLog
+	if declared == "unknown" {
+		pl = parseByBrand(lm, detected)
+	} else {
+		pl = parseByBrand(lm, declared)
+		if pl.URL == "" && detected != declared && detected != "unknown" {
+			pl = parseByBrand(lm, detected)
+		}
+	}
+
+	if declared != "unknown" && detected != declared {
+		pl.DeclaredBrand = declared
+		pl.DetectedBrand = detected
+		pl.Tags = append(pl.Tags, "brand_mismatch")
+		incCounter("brand_mismatch", 1)
+		incCounter(fmt.Sprintf("brand_mismatch_%s_as_%s", declared, detected), 1)
+	}
+
+	normalizeTaxonomy(&pl)
+	return pl
+}
+
+func detectBrand(msg string) string {
+	lowerMsg := strings.ToLower(msg)
 	switch {
 	case strings.Contains(lowerMsg, "urlfilterlog"):
-		return parseRuijie(lm)
+		return "ruijie"
 	case strings.Contains(lowerMsg, "devname=") || strings.Contains(lowerMsg, "devid="):
+		return "forti"
+	default:
+		return "unknown"
+	}
+}
+
+// normalizeBrand API'den gelen marka adını ("Fortigate", "RUIJIE" vb.) parser adına çevirir.
+func normalizeBrand(brand string) string {
+	b := strings.ToLower(strings.TrimSpace(brand))
+	switch {
+	case strings.Contains(b, "forti"):
+		return "forti"
+	case strings.Contains(b, "ruijie"):
+		return "ruijie"
+	default:
+		return "unknown"
+	}
+}
+
+func parseByBrand(lm LogMessage, brand string) ParsedLog {
+	switch brand {
+	case "ruijie":
+		return parseRuijie(lm)
+	case "forti":
 		return parseForti(lm)
 	default:
-		pl := ParsedLog{
-			Brand:      "unknown",
-			RawMessage: lm.Message,
-			FromHost:   lm.FromHost,
-		}
-		if lm.TimeReported != "" {
-			t, err := time.Parse(time.RFC3339, lm.TimeReported)
-			if err == nil {
-				pl.Timestamp = t
-			}
+		return parseUnknown(lm)
+	}
+}
+
+func parseUnknown(lm LogMessage) ParsedLog {
+	pl := ParsedLog{
+		Brand:      "unknown",
+		RawMessage: lm.Message,
+		FromHost:   lm.FromHost,
+	}
+	if lm.TimeReported != "" {
+		t, err := time.Parse(time.RFC3339, lm.TimeReported)
+		if err == nil {
+			pl.Timestamp = t
 		}
-		return pl
 	}
+	return pl
 }
 
 func parseRuijie(msg LogMessage) ParsedLog {
@@ -66,14 +124,18 @@ func parseRuijie(msg LogMessage) ParsedLog {
 			pl.PolicyName = val
 		case "url":
 			pl.URL = val
+		case "protocol", "proto":
+			pl.ProtoRaw = val
 		case "action":
+			pl.ActionRaw = val
 			if val == "1" {
 				pl.Action = "allowed"
 			} else {
 				pl.Action = "blocked"
 			}
 		case "timestamp":
-			t, err := time.Parse("2006-01-02 15:04:05", val)
+			// Cihaz yerel saatini saat dilimi olmadan yazıyor.
+			t, err := time.ParseInLocation("2006-01-02 15:04:05", val, indexLocation())
 			if err == nil {
 				pl.Timestamp = t
 			}
@@ -128,6 +190,11 @@ func parseForti(msg LogMessage) ParsedLog {
 			pl.User = val
 		case "action":
 			pl.Action = val
+			pl.ActionRaw = val
+		case "proto":
+			pl.ProtoRaw = val
+		case "direction":
+			pl.DirectionRaw = val
 		case "eventtime":
 			parsedEventTime, err := strconv.ParseInt(val, 10, 64)
 			if err == nil {
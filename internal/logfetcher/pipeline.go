@@ -0,0 +1,194 @@
+// internal/logfetcher/pipeline.go
+
+package logfetcher
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+// dateString fonksiyonu, t'nin INDEX_TIMEZONE'daki gününü gün/ay/yıl şeklinde döndürür
+func dateString(t time.Time) string {
+	return t.In(indexLocation()).Format("02-01-2006")
+}
+
+var (
+	indexLocOnce sync.Once
+	indexLoc     *time.Location
+)
+
+// indexLocation INDEX_TIMEZONE'u bir kez yükler; geçersizse yerel saat kullanılır.
+func indexLocation() *time.Location {
+	indexLocOnce.Do(func() {
+		name := config.GetConfig().IndexTimezone
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("Invalid INDEX_TIMEZONE %q, using local time: %v", name, err)
+			loc = time.Local
+		}
+		indexLoc = loc
+	})
+	return indexLoc
+}
+
+// logPipeline bir NAS'tan gelen ham mesajı çözer, parse eder, zenginleştirir ve indeksler.
+// Tüm kaynaklar aynı pipeline'ı kullanır; teslim garantisi (ack/nack) kaynağın
+// sorumluluğundadır.
+type logPipeline struct {
+	source  string // loglarda kaynağı ayırt etmek için (örn. "queue=forti-10.0.0.1-queue")
+	brand   string
+	nasName string
+	charset string
+
+	sink Sink
+
+	// Syslog tarafında aynı pipeline birden çok bağlantıdan kullanılabilir.
+	mismatchLogged atomic.Bool
+}
+
+func newLogPipeline(sink Sink, source, brand, nasName string) *logPipeline {
+	return &logPipeline{
+		source:  source,
+		brand:   brand,
+		nasName: nasName,
+		charset: forcedCharsetFor(nasName),
+		sink:    sink,
+	}
+}
+
+type buildResult int
+
+const (
+	buildOK buildResult = iota
+	// buildSkipped: mesaj geçerli ama URL içermiyor, indekslenmez.
+	buildSkipped
+	// buildUnparseable: hiçbir parser mesajı tanımadı.
+	buildUnparseable
+)
+
+// build ham gövdeden indekslenmeye hazır belgeyi üretir. meta, kaynağın bildiği
+// FromHost/TimeReported gibi alanları taşır; Message alanı gövdeden doldurulur.
+func (p *logPipeline) build(raw []byte, meta LogMessage) (ParsedLog, buildResult) {
+	body := decodeBody(raw, p.charset)
+	if body.Charset != charsetUTF8 {
+		incCounter("charset_transcoded", 1)
+	}
+	if body.InvalidBytes > 0 {
+		incCounter("charset_invalid_messages", 1)
+		incCounter("charset_invalid_bytes", int64(body.InvalidBytes))
+	}
+
+	lm := meta
+	lm.Message = body.Text
+
+	doc := parseWithDeclaredBrand(lm, p.brand)
+	doc.NASName = p.nasName
+	if doc.DetectedBrand != "" && p.mismatchLogged.CompareAndSwap(false, true) {
+		log.Printf("Brand mismatch (%s): declared=%s detected=%s",
+			p.source, doc.DeclaredBrand, doc.DetectedBrand)
+	}
+	if body.Charset != charsetUTF8 {
+		doc.RawCharset = body.Charset
+	}
+	doc.RawMessageB64 = body.OriginalB64
+	doc.InvalidBytes = body.InvalidBytes
+
+	if doc.Brand != "forti" && doc.Brand != "ruijie" {
+		return doc, buildUnparseable
+	}
+	if doc.URL == "" {
+		return doc, buildSkipped
+	}
+
+	enrichDoc(&doc, p.nasName)
+	return doc, buildOK
+}
+
+// index belgeyi pipeline'ın sink'ine (yönlendiriciye) verir. done yazma sonucuyla sink'in
+// goroutine'inden çağrılır; hata isRetryableIndexError ile sınıflandırılabilir.
+func (p *logPipeline) index(doc ParsedLog, done func(error)) {
+	p.sink.Write([]SinkRecord{{Doc: doc, Done: done}})
+}
+
+// docID yeniden teslim edilen ya da tekrar oynatılan kaydın aynı belgeye yazılması için
+// kararlı bir kimlik üretir. Kaynak kaydın konumunu biliyorsa kimlik NAS ve bu konumdan
+// üretilir; aynı saniyede gelen birebir aynı iki gerçek olay ayrı kalır. Bilmiyorsa NAS,
+// ham mesaj ve olay zamanından üretilir; bu durumda aynı zaman birimindeki özdeş olaylar
+// tek belgeye düşer. Olay zamanı okunamamış ve konumu bilinmeyen kayıt için kimlik
+// üretilmez (ok=false): günün bütün özdeş satırları birleşirdi. Olay zamanına göre
+// yönlendirildiği için belge her seferinde aynı günlük indekse gider ve tekrar orada
+// yakalanır; data stream'lerde yalnızca aynı arka indeks içindeki tekrarlar yakalanır.
+func docID(doc ParsedLog) (id string, ok bool) {
+	h := sha256.New()
+	h.Write([]byte(doc.NASName))
+	h.Write([]byte{0})
+	switch {
+	case doc.SourceID != "":
+		h.Write([]byte("src:"))
+		h.Write([]byte(doc.SourceID))
+	case !doc.Timestamp.IsZero():
+		raw := doc.RawMessage
+		if doc.RawMessageB64 != "" {
+			raw = doc.RawMessageB64
+		}
+		h.Write([]byte(raw))
+		h.Write([]byte{0})
+		h.Write([]byte(doc.Timestamp.UTC().Format(time.RFC3339Nano)))
+	default:
+		return "", false
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:18]), true
+}
+
+// nasPipelines kuyruk adı taşımayan kaynaklar (syslog, Kafka) için NAS adına göre pipeline
+// önbelleğidir. NAS listeden çıkarılmış ya da markası değişmişse önbellekteki pipeline atılır.
+type nasPipelines struct {
+	sink   Sink
+	source string // "syslog", "kafka"; log etiketi ve sayaç öneki
+	tag    string
+
+	mu        sync.Mutex
+	pipelines map[string]*logPipeline
+	unknown   map[string]bool
+}
+
+func newNASPipelines(sink Sink, source, tag string) *nasPipelines {
+	return &nasPipelines{
+		sink:      sink,
+		source:    source,
+		tag:       tag,
+		pipelines: make(map[string]*logPipeline),
+		unknown:   make(map[string]bool),
+	}
+}
+
+func (c *nasPipelines) get(nasName string) *logPipeline {
+	nas, ok := lookupNAS(nasName)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !ok {
+		delete(c.pipelines, nasName)
+		incCounter(c.source+"_unknown_source", 1)
+		if !c.unknown[nasName] {
+			log.Printf("%s Dropping messages from unknown source %s (not in NAS list)", c.tag, nasName)
+			c.unknown[nasName] = true
+		}
+		return nil
+	}
+	delete(c.unknown, nasName)
+
+	if p, ok := c.pipelines[nasName]; ok && p.brand == nas.Brand {
+		return p
+	}
+	p := newLogPipeline(c.sink, c.source+"="+nasName, nas.Brand, nas.Nasname)
+	c.pipelines[nasName] = p
+	return p
+}
@@ -0,0 +1,65 @@
+package logfetcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDocID(t *testing.T) {
+	ts := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	base := ParsedLog{NASName: "10.0.0.1", RawMessage: "blocked url=x", Timestamp: ts}
+	with := func(f func(*ParsedLog)) ParsedLog {
+		d := base
+		f(&d)
+		return d
+	}
+
+	tests := []struct {
+		name string
+		a, b ParsedLog
+		same bool
+	}{
+		{"identical content without source id", base, base, true},
+		{"identical content, different offsets",
+			with(func(d *ParsedLog) { d.SourceID = "kafka:t:0:1" }),
+			with(func(d *ParsedLog) { d.SourceID = "kafka:t:0:2" }), false},
+		{"redelivery of same offset",
+			with(func(d *ParsedLog) { d.SourceID = "kafka:t:0:1" }),
+			with(func(d *ParsedLog) { d.SourceID = "kafka:t:0:1" }), true},
+		{"same offset on another nas",
+			with(func(d *ParsedLog) { d.SourceID = "file:1:0" }),
+			with(func(d *ParsedLog) { d.SourceID = "file:1:0"; d.NASName = "10.0.0.2" }), false},
+		{"different timestamps", base, with(func(d *ParsedLog) { d.Timestamp = ts.Add(time.Second) }), false},
+		{"transcoded raw differs", base, with(func(d *ParsedLog) { d.RawMessageB64 = "YmxvY2tlZA" }), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, okA := docID(tt.a)
+			b, okB := docID(tt.b)
+			if !okA || !okB {
+				t.Fatal("no id produced")
+			}
+			if (a == b) != tt.same {
+				t.Fatalf("ids %s and %s, same want %v", a, b, tt.same)
+			}
+		})
+	}
+
+	if _, ok := docID(ParsedLog{NASName: "10.0.0.1", RawMessage: "garbage"}); ok {
+		t.Fatal("id produced for zero timestamp without source id")
+	}
+	if _, ok := docID(ParsedLog{NASName: "10.0.0.1", SourceID: "file:1:0"}); !ok {
+		t.Fatal("no id for zero timestamp with source id")
+	}
+}
+
+func TestSearchSinkAddRequireID(t *testing.T) {
+	s := &searchSink{bulk: &bulkIndexer{queue: make(chan bulkItem, 1)}}
+	doc := ParsedLog{NASName: "10.0.0.1", SourceID: "amqp:q:m1:0", RequireID: true}
+	s.add("idx", doc, doc, false, func(error) {})
+	item := <-s.bulk.queue
+	want, _ := docID(doc)
+	if item.id != want || item.op != "create" {
+		t.Fatalf("id=%q op=%q, want %q create", item.id, item.op, want)
+	}
+}
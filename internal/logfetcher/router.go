@@ -0,0 +1,500 @@
+// internal/logfetcher/router.go
+
+package logfetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"tedalogger-logfetcher/config"
+)
+
+// sinkFactories SINKS'te kullanılabilen hedeflerdir. Fabrika küme hazır olana kadar bloklanır.
+var sinkFactories = map[string]func(cfg *config.Config) Sink{
+	sinkElasticsearch: newElasticsearchSink,
+	sinkOpenSearch:    newOpenSearchSink,
+}
+
+// routerBatch bir sink'e tek Write ile verilen en fazla kayıt sayısıdır.
+const routerBatch = 256
+
+// sinkRouter her belgeyi SINK_ROUTES kurallarına göre bir ya da birkaç sink'e dağıtır. Her
+// sink'in önünde kendi tamponu (SINK_BUFFER_SIZE) ve goroutine'i vardır; yavaşlayan ya da
+// çöken bir sink diğerlerini bekletmez. Belgenin sonucu zorunlu sink'lerden gelir: hepsi
+// yazınca başarı, biri hata verirse o hata. SINK_OPTIONAL'daki sink'lerin hataları yalnızca
+// sayılır ve tamponları dolunca belge onlar için atılır; zorunlu sink'in tamponu dolunca
+// Write bloklanır ve kaynaklar yavaşlar.
+type sinkRouter struct {
+	outputs  []*sinkOutput
+	routes   []sinkRoute
+	defaults []*sinkOutput
+
+	// closeMu Write boyunca okuma, Close'un kuyrukları kapatması için yazma kilidiyle
+	// alınır; böylece kapanmış kuyruğa gönderim olmaz. running çalışan run goroutine'leridir.
+	closeMu   sync.RWMutex
+	closed    bool
+	stopQueue sync.Once
+	running   sync.WaitGroup
+
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+type sinkOutput struct {
+	name     string
+	optional bool
+	queue    chan SinkRecord
+	// pending tamponda ya da sink'in Write çağrısında olan kayıt sayısıdır.
+	pending atomic.Int64
+
+	// ready sink bağlandığında kapanır; sink ancak ondan sonra okunur.
+	ready chan struct{}
+	sink  Sink
+}
+
+// sinkRoute koşulların hepsi tutan belgeyi outputs'a gönderir; outputs boşsa (none) belge
+// hiçbir yere yazılmadan ack'lenir.
+type sinkRoute struct {
+	conds   []routeCond
+	outputs []*sinkOutput
+}
+
+type routeCond struct {
+	field   string // nas, brand, category, action, tag
+	pattern string
+}
+
+var (
+	routerOnce   sync.Once
+	sharedRouter *sinkRouter
+)
+
+// getSinkRouter paylaşılan yönlendiriciyi döndürür. İlk çağrı zorunlu sink'ler bağlanana
+// kadar bekler; böylece ack'i olmayan kaynaklar kümeler hazır olmadan dinlemeye başlamaz.
+// İsteğe bağlı sink'ler arka planda bağlanır, o sırada gelen belgeler tamponlarında bekler.
+func getSinkRouter() *sinkRouter {
+	routerOnce.Do(func() {
+		sharedRouter = newSinkRouter(config.GetConfig())
+	})
+	return sharedRouter
+}
+
+func newSinkRouter(cfg *config.Config) *sinkRouter {
+	optional := make(map[string]bool)
+	for _, name := range cfg.SinkOptional {
+		optional[strings.ToLower(name)] = true
+	}
+	size := cfg.SinkBufferSize
+	if size <= 0 {
+		size = 1
+	}
+
+	r := &sinkRouter{changed: make(chan struct{})}
+	byName := make(map[string]*sinkOutput)
+	names := cfg.Sinks
+	if len(names) == 0 {
+		names = []string{cfg.SinkType}
+	}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if _, ok := sinkFactories[name]; !ok {
+			log.Printf("[Router] Unknown sink %q, skipping", name)
+			continue
+		}
+		if byName[name] != nil {
+			continue
+		}
+		o := &sinkOutput{name: name, optional: optional[name], queue: make(chan SinkRecord, size), ready: make(chan struct{})}
+		byName[name] = o
+		r.outputs = append(r.outputs, o)
+	}
+	if len(r.outputs) == 0 {
+		log.Printf("[Router] No valid sinks configured, using %s", sinkElasticsearch)
+		o := &sinkOutput{name: sinkElasticsearch, queue: make(chan SinkRecord, size), ready: make(chan struct{})}
+		byName[sinkElasticsearch] = o
+		r.outputs = append(r.outputs, o)
+	}
+
+	r.routes = parseSinkRoutes(cfg.SinkRoutes, byName)
+	for _, name := range cfg.SinkDefault {
+		if o := byName[strings.ToLower(name)]; o != nil {
+			r.defaults = append(r.defaults, o)
+		} else {
+			log.Printf("[Router] Unknown sink %q in SINK_DEFAULT, skipping", name)
+		}
+	}
+	if len(r.defaults) == 0 {
+		r.defaults = r.outputs
+	}
+
+	for _, o := range r.outputs {
+		r.running.Add(1)
+		go o.run(r, sinkFactories[o.name], cfg)
+	}
+	for _, o := range r.outputs {
+		if !o.optional {
+			<-o.ready
+		}
+	}
+
+	var labels []string
+	for _, o := range r.outputs {
+		if o.optional {
+			labels = append(labels, o.name+" (optional)")
+		} else {
+			labels = append(labels, o.name)
+		}
+	}
+	log.Printf("[Router] Sinks: %s, %d routing rules", strings.Join(labels, ", "), len(r.routes))
+	return r
+}
+
+// parseSinkRoutes "koşul&koşul=sink+sink" kurallarını çözer. Bilinmeyen alan ya da sink içeren
+// kural, belgeleri yanlış yere göndermemek için bütünüyle atlanır.
+func parseSinkRoutes(specs []string, byName map[string]*sinkOutput) []sinkRoute {
+	var routes []sinkRoute
+	for _, spec := range specs {
+		conds, targets, ok := strings.Cut(spec, "=")
+		if !ok {
+			log.Printf("[Router] Invalid SINK_ROUTES rule %q, skipping", spec)
+			continue
+		}
+
+		var route sinkRoute
+		for _, c := range strings.Split(conds, "&") {
+			field, pattern, _ := strings.Cut(strings.TrimSpace(c), ":")
+			switch field {
+			case "nas", "brand", "category", "action", "tag":
+			default:
+				ok = false
+			}
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				ok = false
+			}
+			route.conds = append(route.conds, routeCond{field: field, pattern: pattern})
+		}
+		for _, name := range strings.Split(targets, "+") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "none" {
+				continue
+			}
+			o := byName[name]
+			if o == nil {
+				ok = false
+				break
+			}
+			route.outputs = append(route.outputs, o)
+		}
+		if !ok {
+			log.Printf("[Router] Invalid SINK_ROUTES rule %q, skipping", spec)
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func (c routeCond) match(doc *ParsedLog) bool {
+	var values []string
+	switch c.field {
+	case "nas":
+		values = []string{doc.NASName}
+	case "brand":
+		values = []string{doc.Brand}
+	case "category":
+		values = []string{doc.URLCategory}
+	case "action":
+		values = []string{doc.Action}
+	case "tag":
+		values = doc.Tags
+	}
+	for _, v := range values {
+		if ok, _ := path.Match(c.pattern, v); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// route belgenin gideceği sink'leri döndürür; ilk tutan kural geçerlidir.
+func (r *sinkRouter) route(doc *ParsedLog) []*sinkOutput {
+	for _, rt := range r.routes {
+		matched := true
+		for _, c := range rt.conds {
+			if !c.match(doc) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rt.outputs
+		}
+	}
+	return r.defaults
+}
+
+func (r *sinkRouter) Name() string { return "router" }
+
+func (r *sinkRouter) Write(recs []SinkRecord) {
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+	for _, rec := range recs {
+		if r.closed {
+			rec.Done(errSinkClosed)
+			continue
+		}
+		r.dispatch(rec)
+	}
+}
+
+func (r *sinkRouter) dispatch(rec SinkRecord) {
+	outs := r.route(&rec.Doc)
+	required := 0
+	for _, o := range outs {
+		if !o.optional {
+			required++
+		}
+	}
+	if required == 0 {
+		// Belgenin sonucunu bekleyen sink yok; isteğe bağlı olanlara yine verilir.
+		rec.Done(nil)
+		for _, o := range outs {
+			o.offer(rec.Doc)
+		}
+		return
+	}
+
+	res := &fanOutResult{remaining: required, done: rec.Done}
+	for _, o := range outs {
+		if !o.optional {
+			o.pending.Add(1)
+			o.queue <- SinkRecord{Doc: rec.Doc, Done: res.finish}
+			continue
+		}
+		o.offer(rec.Doc)
+	}
+}
+
+// offer belgeyi isteğe bağlı sink'in tamponuna koyar; tampon doluysa belge bu sink için atılır.
+func (o *sinkOutput) offer(doc ParsedLog) {
+	o.pending.Add(1)
+	select {
+	case o.queue <- SinkRecord{Doc: doc, Done: o.optionalDone}:
+	default:
+		o.pending.Add(-1)
+		incCounter("sink_"+o.name+"_dropped", 1)
+	}
+}
+
+// fanOutResult zorunlu sink'lerin sonuçlarını toplar; ilk hata belgenin sonucudur.
+type fanOutResult struct {
+	mu        sync.Mutex
+	remaining int
+	err       error
+	done      func(error)
+}
+
+func (f *fanOutResult) finish(err error) {
+	f.mu.Lock()
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	f.remaining--
+	last := f.remaining == 0
+	f.mu.Unlock()
+	if last {
+		f.done(f.err)
+	}
+}
+
+func (o *sinkOutput) optionalDone(err error) {
+	if err != nil {
+		incCounter("sink_"+o.name+"_errors", 1)
+	}
+}
+
+// run sink'e bağlanır ve tampondaki kayıtları sırayla ona verir; kuyruk kapatılıp
+// boşalınca döner.
+func (o *sinkOutput) run(r *sinkRouter, factory func(*config.Config) Sink, cfg *config.Config) {
+	defer r.running.Done()
+	o.sink = factory(cfg)
+	close(o.ready)
+	if hn, ok := o.sink.(healthNotifier); ok && !o.optional {
+		go r.watchHealth(hn)
+	}
+
+	batch := make([]SinkRecord, 0, routerBatch)
+	for rec := range o.queue {
+		batch = append(batch[:0], rec)
+	fill:
+		for len(batch) < cap(batch) {
+			select {
+			case rec := <-o.queue:
+				batch = append(batch, rec)
+			default:
+				break fill
+			}
+		}
+		o.sink.Write(batch)
+		o.pending.Add(-int64(len(batch)))
+	}
+}
+
+// reinstallTemplates bağlı arama sink'lerinin şablonlarını yeniden kurar. Henüz bağlanmamış
+// sink'ler bağlanırken güncel desenlerle kurar.
+func (r *sinkRouter) reinstallTemplates() {
+	for _, o := range r.outputs {
+		if s, ok := o.connected().(*searchSink); ok {
+			s.reinstallTemplates()
+		}
+	}
+}
+
+// connected sink bağlandıysa onu, bağlanmadıysa nil döndürür.
+func (o *sinkOutput) connected() Sink {
+	select {
+	case <-o.ready:
+		return o.sink
+	default:
+		return nil
+	}
+}
+
+// Healthy zorunlu sink'lerin hepsi yazım kabul ediyorsa true döner. Belge yalnızca bazı
+// NAS'lar için o sink'e gitse de tüm tüketiciler duraklatılır.
+func (r *sinkRouter) Healthy() bool {
+	for _, o := range r.outputs {
+		if o.optional {
+			continue
+		}
+		if s := o.connected(); s == nil || !s.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *sinkRouter) healthChanges() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changed
+}
+
+// watchHealth zorunlu bir sink'in sağlık değişikliklerini yönlendiricinin kanalına aktarır.
+// Yeni kanal haber vermeden önce alınır; aradaki değişiklik bekleyenlerin okuduğu durumda
+// zaten görünür.
+func (r *sinkRouter) watchHealth(hn healthNotifier) {
+	ch := hn.healthChanges()
+	for {
+		<-ch
+		ch = hn.healthChanges()
+		r.mu.Lock()
+		close(r.changed)
+		r.changed = make(chan struct{})
+		r.mu.Unlock()
+	}
+}
+
+// Flush tamponlardaki kayıtlar sink'lere verilene kadar bekler, ardından her sink'i Flush
+// eder. Henüz bağlanamamış isteğe bağlı sink'ler beklenmez.
+func (r *sinkRouter) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for _, o := range r.outputs {
+		s := o.connected()
+		if s == nil {
+			continue
+		}
+		for o.pending.Load() > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %w", o.name, ctx.Err())
+			case <-ticker.C:
+			}
+		}
+		if err := s.Flush(ctx); err != nil {
+			return fmt.Errorf("%s: %w", o.name, err)
+		}
+	}
+	return nil
+}
+
+// Close yeni yazımları reddeder, tamponları boşaltır, run goroutine'lerini durdurup
+// bekler ve sink'leri kapatır. Hâlâ bağlanmaya çalışan isteğe bağlı sink'in goroutine'i
+// ctx bitene kadar beklenir.
+func (r *sinkRouter) Close(ctx context.Context) error {
+	r.closeMu.Lock()
+	r.closed = true
+	r.closeMu.Unlock()
+
+	if err := r.Flush(ctx); err != nil {
+		return err
+	}
+	// closed true olduktan sonra Write kuyruklara yazmaz; süren Write'lar yukarıdaki
+	// kilitle beklendi.
+	r.stopQueue.Do(func() {
+		for _, o := range r.outputs {
+			close(o.queue)
+		}
+	})
+	stopped := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return fmt.Errorf("router: %w", ctx.Err())
+	}
+
+	for _, o := range r.outputs {
+		if s := o.connected(); s != nil {
+			if err := s.Close(ctx); err != nil {
+				return fmt.Errorf("%s: %w", o.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// offsetSinkName stream kuyruğu offset'lerinin saklandığı sink'tir: SINKS'teki ilk zorunlu
+// sink (varsayılan SINK_TYPE).
+func offsetSinkName(cfg *config.Config) string {
+	optional := make(map[string]bool)
+	for _, name := range cfg.SinkOptional {
+		optional[strings.ToLower(name)] = true
+	}
+	names := cfg.Sinks
+	if len(names) == 0 {
+		names = []string{cfg.SinkType}
+	}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if _, ok := sinkFactories[name]; ok && !optional[name] {
+			return name
+		}
+	}
+	return sinkElasticsearch
+}
+
+// offsetAPI offsetSinkName kümesinin REST uçlarıdır; o sink zorunlu değilse nil döner.
+func (r *sinkRouter) offsetAPI() *esapi.API {
+	name := offsetSinkName(config.GetConfig())
+	for _, o := range r.outputs {
+		if o.name != name || o.optional {
+			continue
+		}
+		if s, ok := o.connected().(*searchSink); ok {
+			return s.bulk.es
+		}
+	}
+	return nil
+}
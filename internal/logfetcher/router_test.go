@@ -0,0 +1,214 @@
+package logfetcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+func testOutputs(names ...string) map[string]*sinkOutput {
+	byName := make(map[string]*sinkOutput)
+	for _, name := range names {
+		byName[name] = &sinkOutput{name: name, queue: make(chan SinkRecord, 1)}
+	}
+	return byName
+}
+
+func outputNames(outs []*sinkOutput) []string {
+	names := make([]string, 0, len(outs))
+	for _, o := range outs {
+		names = append(names, o.name)
+	}
+	return names
+}
+
+func TestParseSinkRoutes(t *testing.T) {
+	byName := testOutputs("elasticsearch", "opensearch")
+	tests := []struct {
+		spec    string
+		ok      bool
+		conds   int
+		outputs []string
+	}{
+		{"nas:10_0_*=opensearch", true, 1, []string{"opensearch"}},
+		{"brand:forti*&category:Malicious*=Elasticsearch+opensearch", true, 2, []string{"elasticsearch", "opensearch"}},
+		{"tag:test=none", true, 1, []string{}},
+		{"action:deny=opensearch+none", true, 1, []string{"opensearch"}},
+		{"nas:10_0_*", false, 0, nil},
+		{"host:10_0_*=opensearch", false, 0, nil},
+		{"nas:=opensearch", false, 0, nil},
+		{"nas:[=opensearch", false, 0, nil},
+		{"nas:10_0_*=kafka", false, 0, nil},
+		{"nas:10_0_*=opensearch+kafka", false, 0, nil},
+	}
+	for _, tt := range tests {
+		routes := parseSinkRoutes([]string{tt.spec}, byName)
+		if !tt.ok {
+			if len(routes) != 0 {
+				t.Errorf("%q accepted", tt.spec)
+			}
+			continue
+		}
+		if len(routes) != 1 {
+			t.Errorf("%q rejected", tt.spec)
+			continue
+		}
+		got := outputNames(routes[0].outputs)
+		if len(routes[0].conds) != tt.conds || len(got) != len(tt.outputs) {
+			t.Errorf("%q = %d conds, outputs %v; want %d, %v", tt.spec, len(routes[0].conds), got, tt.conds, tt.outputs)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.outputs[i] {
+				t.Errorf("%q outputs = %v, want %v", tt.spec, got, tt.outputs)
+			}
+		}
+	}
+}
+
+func TestSinkRouterRoute(t *testing.T) {
+	byName := testOutputs("elasticsearch", "opensearch")
+	r := &sinkRouter{
+		routes: parseSinkRoutes([]string{
+			"tag:replay=none",
+			"nas:10_0_*&action:deny=opensearch",
+			"nas:10_0_*=elasticsearch+opensearch",
+		}, byName),
+		defaults: []*sinkOutput{byName["elasticsearch"]},
+	}
+	tests := []struct {
+		name string
+		doc  ParsedLog
+		want []string
+	}{
+		{"default", ParsedLog{NASName: "192_168_1_1"}, []string{"elasticsearch"}},
+		{"all conditions", ParsedLog{NASName: "10_0_0_1", Action: "deny"}, []string{"opensearch"}},
+		{"first match wins", ParsedLog{NASName: "10_0_0_1", Action: "allow"}, []string{"elasticsearch", "opensearch"}},
+		{"any tag", ParsedLog{NASName: "10_0_0_1", Action: "deny", Tags: []string{"vip", "replay"}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := outputNames(r.route(&tt.doc))
+			if len(got) != len(tt.want) {
+				t.Fatalf("route = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("route = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFanOutResult(t *testing.T) {
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	var calls int
+	var got error
+	f := &fanOutResult{remaining: 3, done: func(err error) { calls++; got = err }}
+
+	f.finish(nil)
+	f.finish(errFirst)
+	if calls != 0 {
+		t.Fatal("done called before every required sink finished")
+	}
+	f.finish(errSecond)
+	if calls != 1 || got != errFirst {
+		t.Fatalf("done called %d times with %v, want once with first error", calls, got)
+	}
+}
+
+func TestSinkRouterDispatch(t *testing.T) {
+	byName := testOutputs("elasticsearch", "opensearch")
+	byName["opensearch"].optional = true
+	r := &sinkRouter{defaults: []*sinkOutput{byName["elasticsearch"], byName["opensearch"]}}
+
+	// İsteğe bağlı sink'in tamponu doluysa belge onun için atılır, zorunlu sink'i bekler.
+	byName["opensearch"].queue <- SinkRecord{}
+	var result []error
+	r.dispatch(SinkRecord{Done: func(err error) { result = append(result, err) }})
+	if n := byName["opensearch"].pending.Load(); n != 0 {
+		t.Fatalf("optional pending = %d after drop", n)
+	}
+	if len(result) != 0 {
+		t.Fatal("document finished before the required sink wrote it")
+	}
+	rec := <-byName["elasticsearch"].queue
+	rec.Done(nil)
+	if len(result) != 1 || result[0] != nil {
+		t.Fatalf("result = %v", result)
+	}
+
+	// Yalnızca isteğe bağlı sink'lere giden belge hemen başarılı sayılır ve onlara yine verilir.
+	r.defaults = []*sinkOutput{byName["opensearch"]}
+	<-byName["opensearch"].queue
+	result = nil
+	r.dispatch(SinkRecord{Done: func(err error) { result = append(result, err) }})
+	if len(result) != 1 || result[0] != nil {
+		t.Fatalf("result = %v", result)
+	}
+	if len(byName["opensearch"].queue) != 1 {
+		t.Fatal("optional sink did not receive the document")
+	}
+}
+
+type recordingSink struct {
+	mu      sync.Mutex
+	written int
+	closed  bool
+}
+
+func (s *recordingSink) Name() string                    { return "recording" }
+func (s *recordingSink) Flush(ctx context.Context) error { return nil }
+func (s *recordingSink) Healthy() bool                   { return true }
+
+func (s *recordingSink) Write(recs []SinkRecord) {
+	s.mu.Lock()
+	s.written += len(recs)
+	s.mu.Unlock()
+	for _, rec := range recs {
+		rec.Done(nil)
+	}
+}
+
+func (s *recordingSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func TestSinkRouterClose(t *testing.T) {
+	sink := &recordingSink{}
+	o := &sinkOutput{name: "recording", queue: make(chan SinkRecord, 4), ready: make(chan struct{})}
+	r := &sinkRouter{outputs: []*sinkOutput{o}, defaults: []*sinkOutput{o}, changed: make(chan struct{})}
+	r.running.Add(1)
+	go o.run(r, func(*config.Config) Sink { return sink }, nil)
+	<-o.ready
+
+	var result []error
+	done := func(err error) { result = append(result, err) }
+	r.Write([]SinkRecord{{Done: done}, {Done: done}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sink.written != 2 || !sink.closed {
+		t.Fatalf("written=%d closed=%v", sink.written, sink.closed)
+	}
+
+	// Kapandıktan sonra yazım reddedilir ve kapalı kuyruğa gönderilmez; Close tekrar çağrılabilir.
+	r.Write([]SinkRecord{{Done: done}})
+	if len(result) != 3 || result[0] != nil || !errors.Is(result[2], errSinkClosed) {
+		t.Fatalf("result = %v", result)
+	}
+	if err := r.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
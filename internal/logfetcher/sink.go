@@ -0,0 +1,197 @@
+// internal/logfetcher/sink.go
+
+package logfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+// Sink ayrıştırılmış logların yazıldığı hedeftir. Yeni bir hedef (dosya, Kafka, başka bir
+// küme) bu arayüzü uygulayıp sinkFactories'e eklenerek SINKS ve SINK_ROUTES ile kullanılır.
+type Sink interface {
+	Name() string
+	// Write kayıtları sink'in tamponuna alır; tampon doluysa yer açılana kadar bloklanır.
+	// Her kaydın sonucu Done ile sink'in kendi goroutine'inden bildirilir; hata
+	// isRetryableIndexError ile sınıflandırılır. recs dönüşten sonra yeniden kullanılabilir.
+	Write(recs []SinkRecord)
+	// Flush tamponda bekleyen kayıtları hemen gönderir ve sonuçlanmalarını bekler.
+	Flush(ctx context.Context) error
+	// Close yeni kayıt almayı bırakır ve bekleyenleri gönderir.
+	Close(ctx context.Context) error
+	// Healthy hedef yazım kabul ediyorsa true döner.
+	Healthy() bool
+}
+
+// SinkRecord tek bir belge ve sonucunun bildirileceği fonksiyondur.
+type SinkRecord struct {
+	Doc  ParsedLog
+	Done func(err error)
+}
+
+// healthNotifier sağlığı değiştiğinde haber verebilen sink'lerdir. AMQP tüketicisi
+// duraklatıp sürdürmek için bu değişiklikleri bekler.
+type healthNotifier interface {
+	Healthy() bool
+	// healthChanges bir sonraki sağlık değişikliğinde kapanan kanalı döndürür.
+	healthChanges() <-chan struct{}
+}
+
+// errSinkClosed kapatılmış sink'e yazılan kayıtlara verilir; geçici hata sayılır.
+var errSinkClosed = &esIndexError{Status: 503, Msg: "sink closed"}
+
+func newElasticsearchSink(cfg *config.Config) Sink {
+	es := connectESWithRetry("[Sink]")
+	s := &searchSink{name: sinkElasticsearch, bulk: newBulkIndexer(sinkElasticsearch, es.API, cfg)}
+	if cfg.ESTemplatesInstall {
+		s.installTemplates = func() error { return InstallTemplates(es) }
+		s.reinstallTemplates()
+	}
+	return s
+}
+
+func newOpenSearchSink(cfg *config.Config) Sink {
+	c := connectOpenSearchWithRetry("[Sink]")
+	s := &searchSink{name: sinkOpenSearch, bulk: newBulkIndexer(sinkOpenSearch, c.API, cfg)}
+	if cfg.ESTemplatesInstall {
+		s.installTemplates = func() error { return InstallOpenSearchTemplates(c) }
+		s.reinstallTemplates()
+	}
+	return s
+}
+
+// searchSink Elasticsearch ya da OpenSearch kümesine _bulk ile yazar. İndeks adı, şema ve
+// belge kimliği burada belirlenir; yeniden deneme, devre kesici ve spool bulkIndexer'dadır.
+type searchSink struct {
+	name   string
+	bulk   *bulkIndexer
+	closed atomic.Bool
+
+	streams sync.Map // data stream adı -> *streamSetup
+
+	// installTemplates kümenin şablonlarını kurar; ES_TEMPLATES_INSTALL kapalıysa nil.
+	installTemplates func() error
+	templatesMu      sync.Mutex
+}
+
+// reinstallTemplates şablonları yeniden kurar; NAS listesine yeni NAS geldiğinde de çağrılır.
+func (s *searchSink) reinstallTemplates() {
+	if s.installTemplates == nil {
+		return
+	}
+	s.templatesMu.Lock()
+	defer s.templatesMu.Unlock()
+	if err := s.installTemplates(); err != nil {
+		log.Printf("[Templates] Install error (%s), indices will use dynamic mapping: %v", s.name, err)
+	}
+}
+
+func (s *searchSink) Name() string { return s.name }
+
+func (s *searchSink) Write(recs []SinkRecord) {
+	for _, rec := range recs {
+		if s.closed.Load() {
+			rec.Done(errSinkClosed)
+			continue
+		}
+		s.index(rec.Doc, rec.Done)
+	}
+}
+
+func (s *searchSink) Flush(ctx context.Context) error {
+	return s.bulk.flushAll(ctx)
+}
+
+func (s *searchSink) Close(ctx context.Context) error {
+	s.closed.Store(true)
+	return s.bulk.flushAll(ctx)
+}
+
+func (s *searchSink) Healthy() bool {
+	return s.bulk.breaker.closed()
+}
+
+func (s *searchSink) healthChanges() <-chan struct{} {
+	return s.bulk.breaker.changes()
+}
+
+// indexName belgeyi olay zamanının gününe ait indekse yönlendirir; geç gelen ya da yeniden
+// oynatılan loglar da kendi günlerinin 5651 dışa aktarımına girer. Zamanı okunamamış belge
+// alındığı ana göre yazılır. INDEX_MAX_FUTURE/INDEX_MAX_PAST dışındaki olaylar (bozuk cihaz
+// saati) geçmiş ya da gelecek günlerin indekslerini açmasın diye karantinaya gider.
+func (s *searchSink) indexName(doc ParsedLog) string {
+	cfg := config.GetConfig()
+	now := time.Now()
+	t := doc.Timestamp
+	if t.IsZero() {
+		t = now
+	}
+	if (cfg.IndexMaxFuture > 0 && t.Sub(now) > cfg.IndexMaxFuture) ||
+		(cfg.IndexMaxPast > 0 && now.Sub(t) > cfg.IndexMaxPast) {
+		incCounter("index_quarantined", 1)
+		return cfg.IndexQuarantine
+	}
+	if dataStreamMode() {
+		return dataStreamName(doc.NASName)
+	}
+
+	return fmt.Sprintf("%s-%s",
+		strings.ReplaceAll(doc.NASName, ".", "_"),
+		dateString(t),
+	)
+}
+
+// index belgeyi bulk kuyruğuna ekler. done yazma sonucuyla bulk worker'ından çağrılır.
+func (s *searchSink) index(doc ParsedLog, done func(error)) {
+	if !dataStreamMode() {
+		indexName := s.indexName(doc)
+		s.add(indexName, encodeDoc(doc, schemaForIndex(indexName)), doc, false, done)
+		return
+	}
+
+	// Data stream belgesi @timestamp olmadan kabul edilmez.
+	if doc.Timestamp.IsZero() {
+		doc.Timestamp = time.Now().UTC()
+	}
+	indexName := s.indexName(doc)
+	if indexName != config.GetConfig().IndexQuarantine {
+		s.ensureDataStream(indexName, doc.NASName)
+	}
+	schema := schemaForIndex(indexName)
+	var v any = encodeDoc(doc, schema)
+	if schema == schemaLegacy {
+		v = legacyStreamDoc{StreamTime: doc.Timestamp, ParsedLog: doc}
+	}
+	s.add(indexName, v, doc, true, done)
+}
+
+// add kodlanmış belgeyi kuyruğa verir. Deterministik kimlik açıksa ve üretilebildiyse belge
+// create ile yazılır; aynı kimlik zaten varsa küme 409 döner ve bulk bunu başarı sayar.
+// Kapalıyken (varsayılan) kimlik verilmez ve aynı kaydın her gönderimi ayrı belge olur;
+// kaynak RequireID istediyse kimlik yine verilir.
+func (s *searchSink) add(indexName string, v any, doc ParsedLog, create bool, done func(error)) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		done(fmt.Errorf("json marshal error: %w", err))
+		return
+	}
+	item := bulkItem{index: indexName, body: data, done: done}
+	if doc.RequireID || config.GetConfig().ESDeterministicIDs {
+		if id, ok := docID(doc); ok {
+			item.id = id
+			create = true
+		}
+	}
+	if create {
+		item.op = "create"
+	}
+	s.bulk.add(item)
+}
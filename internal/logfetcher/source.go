@@ -0,0 +1,135 @@
+// internal/logfetcher/source.go
+
+package logfetcher
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// errUnparseable hiçbir parser'ın tanımadığı mesaj için Nack'e verilir.
+var errUnparseable = errors.New("unparseable: no parser matched message")
+
+// SourceMessage bir kaynaktan okunan tek kayıttır. Meta kaynağın bildiği FromHost/TimeReported
+// gibi alanları taşır; Message alanı Body'den doldurulur. NAS, kuyruk adı taşımayan
+// kaynaklarda (syslog, Kafka, dosya) pipeline'ı seçmek için kullanılır.
+type SourceMessage struct {
+	Body []byte
+	Meta LogMessage
+	NAS  string
+	// ID kaydın kaynaktaki konumudur (Kafka partition/offset, dosya inode/offset, AMQP
+	// message-id); kayıt yeniden teslim edildiğinde ya da tekrar oynatıldığında aynı kalır.
+	// Kaynak böyle bir konum bilmiyorsa boştur.
+	ID string
+	// RequireID kaydın ES_DETERMINISTIC_IDS'ten bağımsız olarak ID'den türetilen kimlikle
+	// yazılmasını ister. Tek kaydının hatası tüm teslimatı yeniden getiren toplu AMQP
+	// gövdelerinde, başarıyla yazılmış kardeş kayıtların ikinci kez yazılmaması için kullanılır.
+	RequireID bool
+
+	// Ack kayıt indekslendiğinde ya da bilerek atlandığında (URL yok, bilinmeyen NAS) çağrılır.
+	Ack func()
+	// Nack kayıt indekslenemediğinde çağrılır. err errUnparseable ya da ES hatasıdır.
+	Nack func(err error, retryable bool)
+}
+
+// Source ham log kayıtlarını handle'a verir. handle beklemeden döner; Ack/Nack kayıt
+// yazıldığında başka bir goroutine'den çağrılır. ctx iptal edildiği için yarıda kalan
+// kayıtta ikisi de çağrılmaz ve kaynak konumunu ilerletmemelidir. handle birden çok
+// goroutine'den çağrılabilir. Run ctx iptal edildiğinde ya da kaynak tükendiğinde (arşiv)
+// nil, bağlantı koptuğunda hata döner.
+type Source interface {
+	Name() string
+	Run(ctx context.Context, handle func(SourceMessage)) error
+}
+
+// sourceProcessor kaynaktan gelen mesajı pipeline'dan geçirip sonucu ack/nack'e çevirir.
+type sourceProcessor struct {
+	kind string // sayaç öneki (amqp, syslog, kafka, file)
+
+	// resolve mesajın pipeline'ını döndürür; nil dönerse mesaj atlanır.
+	resolve func(nas string) *logPipeline
+
+	// retries geçici ES hatalarında mesajın yerinde kaç kez yeniden deneneceğidir; <0
+	// ise ctx iptal edilene kadar denenir. Yeniden teslim edemeyen kaynaklar bunu kullanır.
+	retries int
+}
+
+// newNASProcessor NAS adını mesajdan alan kaynaklar için pipeline önbellekli işlemci kurar.
+func newNASProcessor(sink Sink, kind, tag string, retries int) *sourceProcessor {
+	pipelines := newNASPipelines(sink, kind, tag)
+	return &sourceProcessor{
+		kind:    kind,
+		resolve: pipelines.get,
+		retries: retries,
+	}
+}
+
+// process mesajı parse edip sink'lere verir ve hemen döner; Ack/Nack belge yazıldığında
+// başka bir goroutine'den çağrılır.
+func (sp *sourceProcessor) process(ctx context.Context, m SourceMessage) {
+	p := sp.resolve(m.NAS)
+	if p == nil {
+		m.Ack()
+		return
+	}
+
+	doc, res := p.build(m.Body, m.Meta)
+	doc.SourceID = m.ID
+	doc.RequireID = m.RequireID
+	switch res {
+	case buildUnparseable:
+		incCounter(sp.kind+"_unparseable", 1)
+		m.Nack(errUnparseable, false)
+		return
+	case buildSkipped:
+		m.Ack()
+		return
+	}
+
+	sp.index(ctx, p, doc, m, 0)
+}
+
+func (sp *sourceProcessor) index(ctx context.Context, p *logPipeline, doc ParsedLog, m SourceMessage, attempt int) {
+	p.index(doc, func(err error) {
+		if err == nil {
+			m.Ack()
+			return
+		}
+		// Bulk worker'ı bloklamamak için yeniden deneme ve Nack (DLQ yayını, requeue
+		// gecikmesi) ayrı goroutine'de yapılır.
+		if isRetryableIndexError(err) && (sp.retries < 0 || attempt < sp.retries) {
+			if sp.retries < 0 {
+				log.Printf("ES index error, retrying in place (%s): %v", p.source, err)
+			}
+			go func() {
+				select {
+				case <-ctx.Done():
+					// Kapanış sırasında yazılamadı; kaynak konumu ilerletilmez.
+					return
+				case <-time.After(backoffDelay(attempt)):
+				}
+				sp.index(ctx, p, doc, m, attempt+1)
+			}()
+			return
+		}
+		go m.Nack(err, isRetryableIndexError(err))
+	})
+}
+
+// connectESWithRetry ES'e bağlanana kadar backoff ile dener. Ack'i olmayan kaynaklar
+// ES hazır olmadan dinlemeye başlamaz.
+func connectESWithRetry(tag string) *elasticsearch.Client {
+	for attempt := 0; ; attempt++ {
+		es, err := connectES()
+		if err == nil {
+			return es
+		}
+		delay := backoffDelay(attempt)
+		log.Printf("%s Elasticsearch connection error: %v; retrying in %s", tag, err, delay)
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,487 @@
+// internal/logfetcher/spool.go
+
+package logfetcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+// errSpoolFull ES_SPOOL_MAX_SIZE dolduğunda döner; belgeler eskisi gibi kaynağa nack'lenir.
+var errSpoolFull = errors.New("spool is full")
+
+// errSpoolCorrupt segmentin ortasında bozuk kayıt bulunduğunda döner. Segmentin
+// gönderilmemiş kısmı silinmez, incelenmek üzere kenara alınır.
+var errSpoolCorrupt = errors.New("corrupt spool segment")
+
+const (
+	spoolSegmentExt = ".seg"
+	spoolCorruptExt = ".corrupt"
+	// spoolPosExt boşaltmanın segmentte ulaştığı konumun tutulduğu dosyanın ekidir.
+	spoolPosExt = ".pos"
+
+	// spoolMagic segment dosyasının başıdır. Bu başlığı olmayan segmentler önceki sürümün
+	// biçimindedir (kayıt başlığı 4 bayt uzunluk + 4 bayt CRC32) ve öyle okunur.
+	spoolMagic      = "TLSPOOL2"
+	spoolHeaderSize = int64(len(spoolMagic))
+)
+
+// spool ES'e yazılamayan bulk belgelerini sıralı segment dosyalarında tutar. Segment
+// spoolMagic ile başlar; her kayıt 4 bayt uzunluk, uzunluğun CRC32'si, yükün CRC32'si ve
+// bulk eylem satırı + belge gövdesinden oluşur. Uzunluk da sağlamayla korunduğu için ortada
+// bozulan bir uzunluk yarım yazılmış son kayıtla karıştırılmaz. Yazım her zaman son (aktif)
+// segmente yapılır; boşaltma yalnızca kapatılmış segmentleri okur, her batch'ten sonra
+// ulaştığı konumu yanına yazar ve tamamen gönderilen segmenti siler. Süreç yarıda kalırsa
+// segment kayıtlı konumdan sürer; yalnızca son batch yeniden gönderilebilir (ES_DETERMINISTIC_IDS
+// açıksa 409 ile elenir, kapalıysa o batch'in belgeleri çift yazılır).
+type spool struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+	fsync       string
+
+	mu         sync.Mutex
+	active     *os.File
+	activeSize int64
+	sealedSize int64
+	nextSeq    uint64
+	dirty      bool
+}
+
+type spoolRecord struct {
+	meta []byte
+	body []byte
+}
+
+func openSpool(cfg *config.Config, dir string) (*spool, error) {
+	s := &spool{
+		dir:         dir,
+		segmentSize: cfg.ESSpoolSegmentSize,
+		maxSize:     cfg.ESSpoolMaxSize,
+		fsync:       cfg.ESSpoolFsync,
+		nextSeq:     1,
+	}
+	switch s.fsync {
+	case "always", "interval", "never":
+	default:
+		return nil, fmt.Errorf("invalid ES_SPOOL_FSYNC %q", s.fsync)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	segments, err := s.sealed()
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range segments {
+		fi, err := os.Stat(seg)
+		if err != nil {
+			return nil, err
+		}
+		s.sealedSize += fi.Size()
+		if seq := segmentSeq(seg); seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	if len(segments) > 0 {
+		log.Printf("[Spool] %d segments (%d bytes) pending in %s", len(segments), s.sealedSize, s.dir)
+	}
+
+	if s.fsync == "interval" {
+		go s.syncLoop(cfg.ESSpoolFsyncInterval)
+	}
+	return s, nil
+}
+
+// append belgeleri sırayla aktif segmente yazar. Hata dönerse hiçbiri ack'lenmemelidir.
+func (s *spool) append(items []bulkItem) error {
+	var buf []byte
+	for _, it := range items {
+		payload := make([]byte, 0, len(it.meta)+1+len(it.body))
+		payload = append(payload, it.meta...)
+		payload = append(payload, '\n')
+		payload = append(payload, it.body...)
+
+		var hdr [12]byte
+		binary.BigEndian.PutUint32(hdr[0:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(hdr[4:8], crc32.ChecksumIEEE(hdr[0:4]))
+		binary.BigEndian.PutUint32(hdr[8:12], crc32.ChecksumIEEE(payload))
+		buf = append(buf, hdr[:]...)
+		buf = append(buf, payload...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(len(buf))
+	if s.sealedSize+s.activeSize+n > s.maxSize {
+		incCounter("spool_full", int64(len(items)))
+		return errSpoolFull
+	}
+	if s.active != nil && s.activeSize > 0 && s.activeSize+n > s.segmentSize {
+		if err := s.sealLocked(); err != nil {
+			return err
+		}
+	}
+	if s.active == nil {
+		path := filepath.Join(s.dir, fmt.Sprintf("%016d%s", s.nextSeq, spoolSegmentExt))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		s.nextSeq++
+		if _, err := f.WriteString(spoolMagic); err != nil {
+			f.Close()
+			os.Remove(path)
+			return err
+		}
+		s.active = f
+		s.activeSize = spoolHeaderSize
+	}
+
+	if _, err := s.active.Write(buf); err != nil {
+		s.discardTornLocked()
+		return err
+	}
+	s.activeSize += n
+	if s.fsync == "always" {
+		if err := s.active.Sync(); err != nil {
+			return err
+		}
+	} else {
+		s.dirty = true
+	}
+	incCounter("spool_written", int64(len(items)))
+	return nil
+}
+
+// discardTornLocked başarısız yazımın bıraktığı yarım kaydı siler; sonraki kayıtlar onun
+// arkasına yazılırsa okuyucu bozuk kayıtta durur ve ack'lenmiş belgeler kaybolurdu. Kesilemezse
+// segment kapatılır: yarım kayıt dosyanın sonunda kalır ve okurken atlanır.
+func (s *spool) discardTornLocked() {
+	err := s.active.Truncate(s.activeSize)
+	if err == nil {
+		return
+	}
+	log.Printf("[Spool] Truncate error, rotating segment %s: %v", s.active.Name(), err)
+	if fi, err := s.active.Stat(); err == nil {
+		s.activeSize = fi.Size()
+	}
+	s.active.Close()
+	s.sealedSize += s.activeSize
+	s.active, s.activeSize, s.dirty = nil, 0, false
+}
+
+func (s *spool) syncLoop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for range t.C {
+		s.mu.Lock()
+		if s.dirty && s.active != nil {
+			if err := s.active.Sync(); err != nil {
+				log.Printf("[Spool] fsync error: %v", err)
+			}
+			s.dirty = false
+		}
+		s.mu.Unlock()
+	}
+}
+
+// seal aktif segmenti kapatır; böylece boşaltıcı onu da okuyabilir.
+func (s *spool) seal() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sealLocked()
+}
+
+func (s *spool) sealLocked() error {
+	if s.active == nil || s.activeSize <= spoolHeaderSize {
+		return nil
+	}
+	if s.fsync != "never" {
+		if err := s.active.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := s.active.Close(); err != nil {
+		return err
+	}
+	s.sealedSize += s.activeSize
+	s.active, s.activeSize, s.dirty = nil, 0, false
+	return nil
+}
+
+// sealed kapatılmış segmentleri eskiden yeniye döndürür.
+func (s *spool) sealed() ([]string, error) {
+	s.mu.Lock()
+	var activeName string
+	if s.active != nil {
+		activeName = s.active.Name()
+	}
+	s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+spoolSegmentExt))
+	if err != nil {
+		return nil, err
+	}
+	out := paths[:0]
+	for _, p := range paths {
+		if p != activeName {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return segmentSeq(out[i]) < segmentSeq(out[j]) })
+	return out, nil
+}
+
+// remove tamamen gönderilmiş segmenti ve konum dosyasını siler.
+func (s *spool) remove(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	os.Remove(path + spoolPosExt)
+	s.mu.Lock()
+	s.sealedSize -= fi.Size()
+	s.mu.Unlock()
+	return nil
+}
+
+// drainedTo boşaltmanın segmentte ulaştığı konumdur; kayıt yoksa 0.
+func (s *spool) drainedTo(path string) int64 {
+	data, err := os.ReadFile(path + spoolPosExt)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || n < 0 {
+		log.Printf("[Spool] Ignoring invalid drain position for %s: %q", path, data)
+		return 0
+	}
+	return n
+}
+
+// setDrainedTo gönderilen batch'in sonunu kaydeder; dosya yerine konarak yazıldığı için
+// yarım kalmaz.
+func (s *spool) setDrainedTo(path string, offset int64) error {
+	tmp := path + spoolPosExt + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path+spoolPosExt)
+}
+
+// quarantine bozuk segmenti boşaltma sırasından çıkarır. from'dan önceki kayıtlar gönderilmiş
+// olduğundan yalnızca from'dan sonrası (bozuk kayıt ve arkası) segment başlığıyla birlikte
+// "<segment><spoolCorruptExt>" olarak diskte bırakılır; dosya yeniden oynatılırsa
+// gönderilmiş belgeler tekrar yazılmaz.
+func (s *spool) quarantine(path string, from int64) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := copySegmentTail(path, path+spoolCorruptExt, from); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	os.Remove(path + spoolPosExt)
+	s.mu.Lock()
+	s.sealedSize -= fi.Size()
+	s.mu.Unlock()
+	return nil
+}
+
+func copySegmentTail(src, dst string, from int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// Başlık (varsa) korunur ki kalan kayıtlar aynı biçimde okunabilsin.
+	head, err := segmentDataStart(in)
+	if err == nil && from < head {
+		from = head
+	}
+	if err == nil && head > 0 {
+		_, err = out.WriteString(spoolMagic)
+	}
+	if err == nil {
+		_, err = io.Copy(out, io.NewSectionReader(in, from, 1<<62))
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// segmentDataStart ilk kaydın konumudur: spoolMagic ile başlayan segmentte başlığın sonu,
+// önceki biçimde 0.
+func segmentDataStart(f *os.File) (int64, error) {
+	var magic [len(spoolMagic)]byte
+	n, err := f.ReadAt(magic[:], 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if n == len(magic) && string(magic[:]) == spoolMagic {
+		return spoolHeaderSize, nil
+	}
+	return 0, nil
+}
+
+// pending boşaltılmayı bekleyen bayt sayısıdır.
+func (s *spool) pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.activeSize <= spoolHeaderSize {
+		return s.sealedSize
+	}
+	return s.sealedSize + s.activeSize
+}
+
+func segmentSeq(path string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), spoolSegmentExt), 10, 64)
+	return n
+}
+
+// readSegment segmentteki kayıtları start konumundan itibaren sırayla batch boyutunda fn'e
+// verir; next, verilen son kaydın bittiği konumdur. Dosyanın sonundaki yarım ya da bozuk
+// kayıt (yazım sırasında çökme) hiç ack'lenmediği için atlanır. Arkasında başka veri olan
+// bozuk kayıtta, o noktaya kadarki kayıtlar fn'e verildikten sonra errSpoolCorrupt döner.
+func readSegment(path string, start int64, batch int, fn func(recs []spoolRecord, next int64) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	head, err := segmentDataStart(f)
+	if err != nil {
+		return err
+	}
+	// Önceki biçimde uzunluğun sağlaması yoktur.
+	checked := head > 0
+	hdrSize := int64(12)
+	if !checked {
+		hdrSize = 8
+	}
+	if start < head {
+		start = head
+	}
+
+	r := bufio.NewReaderSize(io.NewSectionReader(f, start, fi.Size()-start), 1<<20)
+	var recs []spoolRecord
+	offset := start
+	corrupt := func(format string, args ...any) error {
+		if len(recs) > 0 {
+			if err := fn(recs, offset); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("%w: "+format, append([]any{errSpoolCorrupt}, args...)...)
+	}
+	for {
+		hdr := make([]byte, hdrSize)
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			if err != io.EOF {
+				log.Printf("[Spool] %s: truncated record header at offset %d", path, offset)
+			}
+			break
+		}
+		size := binary.BigEndian.Uint32(hdr[0:4])
+		if checked && crc32.ChecksumIEEE(hdr[0:4]) != binary.BigEndian.Uint32(hdr[4:8]) {
+			// Çökmeden sonra dosya sistemi kuyruğu sıfırla doldurmuş olabilir.
+			if zeroFrom(f, offset, fi.Size()) {
+				log.Printf("[Spool] %s: zero-filled tail at offset %d", path, offset)
+				break
+			}
+			return corrupt("bad record header at offset %d", offset)
+		}
+		if int64(size) > fi.Size()-offset-hdrSize {
+			// Uzunluk sağlamsa bu gerçekten yarım yazılmış son kayıttır.
+			log.Printf("[Spool] %s: truncated record at offset %d", path, offset)
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			log.Printf("[Spool] %s: truncated record at offset %d", path, offset)
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(hdr[hdrSize-4:]) {
+			if offset+hdrSize+int64(size) < fi.Size() {
+				return corrupt("checksum mismatch at offset %d", offset)
+			}
+			log.Printf("[Spool] %s: checksum mismatch at offset %d", path, offset)
+			break
+		}
+		offset += hdrSize + int64(size)
+
+		nl := bytes.IndexByte(payload, '\n')
+		if nl < 0 {
+			continue
+		}
+		recs = append(recs, spoolRecord{meta: payload[:nl], body: payload[nl+1:]})
+		if len(recs) >= batch {
+			if err := fn(recs, offset); err != nil {
+				return err
+			}
+			recs = nil
+		}
+	}
+	if len(recs) > 0 {
+		return fn(recs, offset)
+	}
+	return nil
+}
+
+// zeroFrom dosyanın from'dan sonuna kadar sıfır olup olmadığını döndürür.
+func zeroFrom(f *os.File, from, size int64) bool {
+	buf := make([]byte, 64<<10)
+	for from < size {
+		n, err := f.ReadAt(buf, from)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return false
+			}
+		}
+		from += int64(n)
+		if err != nil {
+			return err == io.EOF && from >= size
+		}
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package logfetcher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+func testSpool(t *testing.T) *spool {
+	t.Helper()
+	s, err := openSpool(&config.Config{
+		ESSpoolSegmentSize:   1 << 20,
+		ESSpoolMaxSize:       1 << 30,
+		ESSpoolFsync:         "never",
+		ESSpoolFsyncInterval: time.Second,
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func spoolItems(bodies ...string) []bulkItem {
+	items := make([]bulkItem, len(bodies))
+	for i, b := range bodies {
+		items[i] = bulkItem{meta: []byte(`{"index":{}}`), body: []byte(b)}
+	}
+	return items
+}
+
+func readBodies(t *testing.T, path string) ([]string, error) {
+	t.Helper()
+	var out []string
+	err := readSegment(path, 0, 2, func(recs []spoolRecord, next int64) error {
+		for _, r := range recs {
+			out = append(out, string(r.body))
+		}
+		return nil
+	})
+	return out, err
+}
+
+func TestReadSegment(t *testing.T) {
+	tests := []struct {
+		name    string
+		mangle  func(data []byte) []byte
+		want    int
+		corrupt bool
+	}{
+		{"intact", func(d []byte) []byte { return d }, 3, false},
+		{"torn header at tail", func(d []byte) []byte { return append(d, 0, 0, 0) }, 3, false},
+		{"torn payload at tail", func(d []byte) []byte { return d[:len(d)-2] }, 2, false},
+		{"checksum mismatch at tail", func(d []byte) []byte { d[len(d)-1] ^= 0xff; return d }, 2, false},
+		{"zero-filled tail", func(d []byte) []byte { return append(d, make([]byte, 100)...) }, 3, false},
+		// Her kayıt 32 bayt: 12 bayt başlık, 20 bayt yük; ilk kayıt 8. bayttadır.
+		{"header corrupt in first record", func(d []byte) []byte { d[9] ^= 0xff; return d }, 0, true},
+		{"checksum mismatch in middle", func(d []byte) []byte { d[60] ^= 0xff; return d }, 1, true},
+		// Ortadaki kaydın uzunluğu dev bir değere bozulursa yarım son kayıt sanılmamalı.
+		{"length corrupt in middle", func(d []byte) []byte { d[40] = 0x7f; return d }, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSpool(t)
+			if err := s.append(spoolItems(`{"n":1}`, `{"n":2}`, `{"n":3}`)); err != nil {
+				t.Fatal(err)
+			}
+			path := s.active.Name()
+			if err := s.seal(); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.mangle(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readBodies(t, path)
+			if tt.corrupt != errors.Is(err, errSpoolCorrupt) {
+				t.Fatalf("err = %v, corrupt want %v", err, tt.corrupt)
+			}
+			if !tt.corrupt && err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("read %d records %v, want %d", len(got), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpoolAppendAfterFailedWrite(t *testing.T) {
+	s := testSpool(t)
+	if err := s.append(spoolItems(`{"n":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	first := s.active.Name()
+
+	// Yazım hatası: yarım kayıt kesilemiyorsa segment kapatılıp yenisine geçilmeli.
+	s.active.Close()
+	if err := s.append(spoolItems(`{"n":2}`)); err == nil {
+		t.Fatal("append on closed segment succeeded")
+	}
+	if err := s.append(spoolItems(`{"n":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if s.active.Name() == first {
+		t.Fatal("append did not rotate to a new segment")
+	}
+	if err := s.seal(); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, err := s.sealed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("segments = %v, want 2", segments)
+	}
+	var got []string
+	for _, seg := range segments {
+		bodies, err := readBodies(t, seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, bodies...)
+	}
+	if len(got) != 2 || got[0] != `{"n":1}` || got[1] != `{"n":3}` {
+		t.Fatalf("records = %v", got)
+	}
+}
+
+// sealedSegment üç kayıtlı kapatılmış bir segment yazar.
+func sealedSegment(t *testing.T, s *spool) string {
+	t.Helper()
+	if err := s.append(spoolItems(`{"n":1}`, `{"n":2}`, `{"n":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	path := s.active.Name()
+	if err := s.seal(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestSpoolQuarantine bozuk segmentin yalnızca gönderilmemiş kısmının kenara alındığını sınar.
+func TestSpoolQuarantine(t *testing.T) {
+	s := testSpool(t)
+	path := sealedSegment(t, s)
+	data, _ := os.ReadFile(path)
+	data[60] ^= 0xff // ikinci kaydın yükü
+	os.WriteFile(path, data, 0o644)
+
+	var sent []string
+	done := s.drainedTo(path)
+	err := readSegment(path, done, 1, func(recs []spoolRecord, next int64) error {
+		for _, r := range recs {
+			sent = append(sent, string(r.body))
+		}
+		done = next
+		return s.setDrainedTo(path, next)
+	})
+	if !errors.Is(err, errSpoolCorrupt) || len(sent) != 1 || done != 40 {
+		t.Fatalf("err = %v, sent = %v, done = %d", err, sent, done)
+	}
+	if err := s.quarantine(path, done); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.pending() != 0 {
+		t.Fatalf("pending = %d after quarantine", s.pending())
+	}
+	if segments, _ := s.sealed(); len(segments) != 0 {
+		t.Fatalf("quarantined segment still listed: %v", segments)
+	}
+	for _, p := range []string{path, path + spoolPosExt} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s left behind: %v", p, err)
+		}
+	}
+	kept, err := os.ReadFile(path + spoolCorruptExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte(spoolMagic), data[40:]...); !bytes.Equal(kept, want) {
+		t.Fatalf("quarantined %d bytes, want the %d unsent bytes", len(kept), len(want))
+	}
+}
+
+// TestSpoolResume boşaltmanın kayıtlı konumdan sürdüğünü sınar.
+func TestSpoolResume(t *testing.T) {
+	s := testSpool(t)
+	path := sealedSegment(t, s)
+	if err := s.setDrainedTo(path, 40); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err := readSegment(path, s.drainedTo(path), 10, func(recs []spoolRecord, next int64) error {
+		for _, r := range recs {
+			got = append(got, string(r.body))
+		}
+		return nil
+	})
+	if err != nil || len(got) != 2 || got[0] != `{"n":2}` {
+		t.Fatalf("records = %v, %v", got, err)
+	}
+	if err := s.remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + spoolPosExt); !os.IsNotExist(err) {
+		t.Fatalf("position file left behind: %v", err)
+	}
+}
+
+// TestReadSegmentLegacy başlıksız, önceki biçimdeki segmentlerin okunduğunu sınar.
+func TestReadSegmentLegacy(t *testing.T) {
+	var data []byte
+	for _, body := range []string{`{"n":1}`, `{"n":2}`} {
+		payload := []byte(`{"index":{}}` + "\n" + body)
+		var hdr [8]byte
+		binary.BigEndian.PutUint32(hdr[0:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(hdr[4:8], crc32.ChecksumIEEE(payload))
+		data = append(append(data, hdr[:]...), payload...)
+	}
+	path := filepath.Join(t.TempDir(), "0000000000000001"+spoolSegmentExt)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBodies(t, path)
+	if err != nil || len(got) != 2 || got[1] != `{"n":2}` {
+		t.Fatalf("records = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,56 @@
+// internal/logfetcher/stats.go
+
+package logfetcher
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var counters sync.Map // string -> *atomic.Int64
+
+func incCounter(name string, delta int64) {
+	v, ok := counters.Load(name)
+	if !ok {
+		v, _ = counters.LoadOrStore(name, new(atomic.Int64))
+	}
+	v.(*atomic.Int64).Add(delta)
+}
+
+func counterSnapshot() map[string]int64 {
+	out := make(map[string]int64)
+	counters.Range(func(k, v any) bool {
+		out[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	return out
+}
+
+// reportCounters sayaçları belirli aralıklarla tek satır halinde loglar.
+func reportCounters(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for range t.C {
+		snap := counterSnapshot()
+		if len(snap) == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(snap))
+		for k := range snap {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, fmt.Sprintf("%s=%d", k, snap[k]))
+		}
+		log.Printf("[Stats] %s", strings.Join(parts, " "))
+	}
+}
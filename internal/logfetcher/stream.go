@@ -0,0 +1,488 @@
+// internal/logfetcher/stream.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/streadway/amqp"
+	"tedalogger-logfetcher/config"
+)
+
+const streamCommitInterval = 5 * time.Second
+
+// errStreamOffsetChanged, offset kaydı tüketici dışında (streamctl) değiştirildiğinde döner;
+// oturum kapanır ve supervisor yeni konumdan yeniden başlatır.
+var errStreamOffsetChanged = fmt.Errorf("stream offset changed externally")
+
+func isStreamQueue(queue string) bool {
+	for _, pattern := range config.GetConfig().RabbitMQStreamQueues {
+		if ok, _ := path.Match(pattern, queue); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// streamPosition stream kuyruğunda bir tüketici adının kaldığı yerdir.
+type streamPosition struct {
+	Queue      string `json:"queue"`
+	Consumer   string `json:"consumer"`
+	NextOffset int64  `json:"next_offset"`
+	// Start doluysa (index deposunda streamctl ile ayarlanır) NextOffset yerine bu konumdan
+	// başlanır.
+	Start     string `json:"start,omitempty"`
+	UpdatedBy string `json:"updated_by"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// streamOffsetStore bir tüketici adının stream'deki konumunu saklar. Tüketim her iki
+// durumda da AMQP ile x-stream-offset kullanılarak yapılır.
+//
+// broker (varsayılan): offset RabbitMQ'da stream protokolünün StoreOffset/QueryOffset
+// komutlarıyla tüketici adına saklanır (rabbitmq-streams list_consumer_offsets gösterir).
+// Saklanan değer bir sonraki okunacak offset'tir, böylece 0'dan başlamak da yazılabilir.
+//
+// index: offset zorunlu ES/OpenSearch sink'inin RABBITMQ_STREAM_OFFSET_INDEX indeksinde
+// tutulur; konum belgelerle aynı kümede ilerler ve küme geri yüklenirse onunla geri gelir.
+type streamOffsetStore interface {
+	// load kayıtlı konumu döndürür; kayıt yoksa nil.
+	load() (*streamPosition, error)
+	// save bir sonraki okunacak offset'i yazar. Konum bu oturum dışında (streamctl)
+	// değiştirildiyse errStreamOffsetChanged döner.
+	save(next int64) error
+	close()
+}
+
+type streamTracker struct {
+	store streamOffsetStore
+
+	// Teslimatlar bulk yazıcıda sırasız sonuçlanabilir; next yalnızca kendisinden önceki
+	// tüm teslimatlar sonuçlandığında ilerler.
+	mu       sync.Mutex
+	inflight []*streamOffset
+	next     int64 // işlenmiş son offset + 1
+	pending  int
+
+	committed int64
+}
+
+type streamOffset struct {
+	off  int64
+	done bool
+}
+
+func streamDocID(consumer, queue string) string {
+	return consumer + ":" + queue
+}
+
+func newStreamTracker(store streamOffsetStore) *streamTracker {
+	return &streamTracker{store: store, next: -1}
+}
+
+// newStreamOffsetStore RABBITMQ_STREAM_OFFSET_STORE'a göre konum deposunu kurar. index
+// deposu zorunlu bir ES/OpenSearch sink'i (es) gerektirir.
+func newStreamOffsetStore(cfg *config.Config, es *esapi.API, queue string) (streamOffsetStore, error) {
+	consumer := cfg.RabbitMQStreamConsumerName
+	if strings.EqualFold(cfg.RabbitMQStreamOffsetStore, "index") {
+		if es == nil {
+			return nil, fmt.Errorf("stream queue needs a required elasticsearch or opensearch sink for offsets (RABBITMQ_STREAM_OFFSET_STORE=index)")
+		}
+		return &indexOffsetStore{es: es, index: cfg.RabbitMQStreamOffsetIndex, queue: queue, consumer: consumer}, nil
+	}
+	return &brokerOffsetStore{
+		dial:     func() (*streamConn, error) { return dialStream(cfg, cfg.RabbitMQConnectionName+"-offsets") },
+		queue:    queue,
+		consumer: consumer,
+		stored:   -1,
+	}, nil
+}
+
+// startArg Consume'a verilecek x-stream-offset değerini belirler. Kayıtlı konum yoksa
+// RABBITMQ_STREAM_START kullanılır.
+func (t *streamTracker) startArg() (interface{}, error) {
+	pos, err := t.store.load()
+	if err != nil {
+		return nil, err
+	}
+	if pos != nil {
+		if pos.Start != "" {
+			return parseStreamStart(pos.Start)
+		}
+		if pos.NextOffset >= 0 {
+			t.next, t.committed = pos.NextOffset, pos.NextOffset
+			return pos.NextOffset, nil
+		}
+	}
+	return parseStreamStart(config.GetConfig().RabbitMQStreamStart)
+}
+
+// parseStreamStart "first", "last", "next", "offset:N" ya da "timestamp:RFC3339" kabul eder.
+func parseStreamStart(spec string) (interface{}, error) {
+	switch {
+	case spec == "first" || spec == "last" || spec == "next":
+		return spec, nil
+	case strings.HasPrefix(spec, "offset:"):
+		n, err := strconv.ParseInt(strings.TrimPrefix(spec, "offset:"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stream offset %q: %w", spec, err)
+		}
+		return n, nil
+	case strings.HasPrefix(spec, "timestamp:"):
+		ts, err := time.Parse(time.RFC3339, strings.TrimPrefix(spec, "timestamp:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid stream timestamp %q: %w", spec, err)
+		}
+		return ts, nil
+	default:
+		return nil, fmt.Errorf("invalid stream start %q", spec)
+	}
+}
+
+// begin teslimatı sıraya alır; dönen fonksiyon teslimat ack'lendiğinde ya da
+// reddedildiğinde çağrılır.
+func (t *streamTracker) begin(d amqp.Delivery) func() {
+	off, ok := d.Headers["x-stream-offset"].(int64)
+	if !ok {
+		return func() {}
+	}
+	so := &streamOffset{off: off}
+	t.mu.Lock()
+	t.inflight = append(t.inflight, so)
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		so.done = true
+		for len(t.inflight) > 0 && t.inflight[0].done {
+			t.next = t.inflight[0].off + 1
+			t.inflight = t.inflight[1:]
+			t.pending++
+		}
+	}
+}
+
+func (t *streamTracker) shouldCommit() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pending >= config.GetConfig().RabbitMQStreamCommitEvery
+}
+
+// commit işlenen son offset'i yazar. Kayıt bu oturum dışında değiştiyse
+// errStreamOffsetChanged döner.
+func (t *streamTracker) commit() error {
+	t.mu.Lock()
+	next, pending := t.next, t.pending
+	t.mu.Unlock()
+
+	if next < 0 || next == t.committed {
+		return nil
+	}
+	if err := t.store.save(next); err != nil {
+		return err
+	}
+	t.committed = next
+
+	t.mu.Lock()
+	t.pending -= pending
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *streamTracker) close() { t.store.close() }
+
+// indexOffsetStore konumu ES/OpenSearch'te tüketici adı ve kuyruk başına bir belgede tutar;
+// dış değişiklik optimistic concurrency (if_seq_no) ile fark edilir.
+type indexOffsetStore struct {
+	es       *esapi.API
+	index    string
+	queue    string
+	consumer string
+
+	seqNo       int
+	primaryTerm int
+	exists      bool
+}
+
+func (t *indexOffsetStore) load() (*streamPosition, error) {
+	res, err := t.es.Get(t.index, streamDocID(t.consumer, t.queue))
+	if err != nil {
+		return nil, fmt.Errorf("stream offset get error: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		t.exists = false
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("stream offset get response error: %s", res.String())
+	}
+
+	var parsed struct {
+		SeqNo       int            `json:"_seq_no"`
+		PrimaryTerm int            `json:"_primary_term"`
+		Source      streamPosition `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("stream offset parse error: %w", err)
+	}
+	t.seqNo, t.primaryTerm, t.exists = parsed.SeqNo, parsed.PrimaryTerm, true
+	return &parsed.Source, nil
+}
+
+func (t *indexOffsetStore) save(next int64) error {
+	pos := streamPosition{
+		Queue:      t.queue,
+		Consumer:   t.consumer,
+		NextOffset: next,
+		UpdatedBy:  "consumer",
+		UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	seq, term, err := writeStreamPosition(t.es, t.index, pos, t.exists, t.seqNo, t.primaryTerm)
+	if err != nil {
+		return err
+	}
+	t.seqNo, t.primaryTerm, t.exists = seq, term, true
+	return nil
+}
+
+func (t *indexOffsetStore) close() {}
+
+// brokerOffsetStore konumu RabbitMQ'da stream protokolüyle saklar. Bağlantı ilk kullanımda
+// açılır ve hata alınca bir sonraki kullanımda yeniden kurulur. StoreOffset'in yanıtı
+// olmadığından dış değişiklik, yazmadan önce okunan değerin bu oturumun son bildiğiyle
+// karşılaştırılmasıyla fark edilir.
+type brokerOffsetStore struct {
+	dial     func() (*streamConn, error)
+	conn     *streamConn
+	queue    string
+	consumer string
+	// stored bu oturumun okuduğu ya da yazdığı son değerdir; kayıt yoksa -1.
+	stored int64
+}
+
+func (t *brokerOffsetStore) connect() (*streamConn, error) {
+	if t.conn == nil {
+		c, err := t.dial()
+		if err != nil {
+			return nil, err
+		}
+		t.conn = c
+	}
+	return t.conn, nil
+}
+
+// query kayıtlı offset'i okur; kayıt yoksa -1. Hata alınırsa bağlantı bırakılır.
+func (t *brokerOffsetStore) query() (int64, error) {
+	c, err := t.connect()
+	if err != nil {
+		return 0, err
+	}
+	off, ok, err := c.queryOffset(t.consumer, t.queue)
+	if err != nil {
+		t.close()
+		return 0, fmt.Errorf("stream offset query error: %w", err)
+	}
+	if !ok {
+		return -1, nil
+	}
+	return off, nil
+}
+
+func (t *brokerOffsetStore) load() (*streamPosition, error) {
+	off, err := t.query()
+	if err != nil {
+		return nil, err
+	}
+	t.stored = off
+	if off < 0 {
+		return nil, nil
+	}
+	return &streamPosition{Queue: t.queue, Consumer: t.consumer, NextOffset: off}, nil
+}
+
+func (t *brokerOffsetStore) save(next int64) error {
+	cur, err := t.query()
+	if err != nil {
+		return err
+	}
+	if cur != t.stored {
+		return errStreamOffsetChanged
+	}
+	if err := t.conn.storeOffset(t.consumer, t.queue, next); err != nil {
+		t.close()
+		return fmt.Errorf("stream offset store error: %w", err)
+	}
+	t.stored = next
+	return nil
+}
+
+func (t *brokerOffsetStore) close() {
+	if t.conn != nil {
+		t.conn.close()
+		t.conn = nil
+	}
+}
+
+func writeStreamPosition(es *esapi.API, index string, pos streamPosition, exists bool, seqNo, primaryTerm int) (int, int, error) {
+	data, _ := json.Marshal(pos)
+
+	opts := []func(*esapi.IndexRequest){
+		es.Index.WithDocumentID(streamDocID(pos.Consumer, pos.Queue)),
+		es.Index.WithContext(context.Background()),
+	}
+	if exists {
+		opts = append(opts, es.Index.WithIfSeqNo(seqNo), es.Index.WithIfPrimaryTerm(primaryTerm))
+	} else {
+		opts = append(opts, es.Index.WithOpType("create"))
+	}
+
+	res, err := es.Index(index, bytes.NewReader(data), opts...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("stream offset write error: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		return 0, 0, errStreamOffsetChanged
+	}
+	if res.IsError() {
+		return 0, 0, fmt.Errorf("stream offset write response error: %s", res.String())
+	}
+
+	var parsed struct {
+		SeqNo       int `json:"_seq_no"`
+		PrimaryTerm int `json:"_primary_term"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return 0, 0, fmt.Errorf("stream offset write parse error: %w", err)
+	}
+	return parsed.SeqNo, parsed.PrimaryTerm, nil
+}
+
+// SetStreamPosition bir stream kuyruğunun tüketici konumunu değiştirir. start
+// "first", "last", "next", "offset:N" ya da "timestamp:RFC3339" olabilir. Çalışan tüketici
+// bir sonraki commit'te değişikliği fark eder ve yeni konumdan yeniden başlar.
+func SetStreamPosition(queue, consumer, start string) error {
+	arg, err := parseStreamStart(start)
+	if err != nil {
+		return err
+	}
+	cfg := config.GetConfig()
+	if consumer == "" {
+		consumer = cfg.RabbitMQStreamConsumerName
+	}
+	if !strings.EqualFold(cfg.RabbitMQStreamOffsetStore, "index") {
+		return setBrokerStreamPosition(cfg, queue, consumer, start, arg)
+	}
+
+	es, err := connectCluster()
+	if err != nil {
+		return err
+	}
+
+	pos := streamPosition{
+		Queue:      queue,
+		Consumer:   consumer,
+		NextOffset: -1,
+		Start:      start,
+		UpdatedBy:  "streamctl",
+		UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	data, _ := json.Marshal(pos)
+	res, err := es.Index(cfg.RabbitMQStreamOffsetIndex, bytes.NewReader(data),
+		es.Index.WithDocumentID(streamDocID(consumer, queue)),
+		es.Index.WithRefresh("true"),
+	)
+	if err != nil {
+		return fmt.Errorf("stream offset write error: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("stream offset write response error: %s", res.String())
+	}
+
+	log.Printf("[Stream] %s position for %s set to %s", queue, consumer, start)
+	return nil
+}
+
+// setBrokerStreamPosition konumu broker'a yazar. Broker yalnızca sayısal offset sakladığından
+// first, last ve timestamp kuyruktan o konumdaki ilk mesaj okunarak offset'e çevrilir; next
+// (henüz yazılmamış ilk mesaj) çevrilemez.
+func setBrokerStreamPosition(cfg *config.Config, queue, consumer, start string, arg interface{}) error {
+	next, ok := arg.(int64)
+	if !ok {
+		if start == "next" {
+			return fmt.Errorf("start %q cannot be stored on the broker; use RABBITMQ_STREAM_START=next with a new RABBITMQ_STREAM_CONSUMER_NAME", start)
+		}
+		off, err := resolveStreamOffset(queue, arg)
+		if err != nil {
+			return err
+		}
+		next = off
+	}
+
+	c, err := dialStream(cfg, cfg.RabbitMQConnectionName+"-streamctl")
+	if err != nil {
+		return err
+	}
+	if err := c.storeOffset(consumer, queue, next); err != nil {
+		c.close()
+		return fmt.Errorf("stream offset store error: %w", err)
+	}
+	// Close'un yanıtı StoreOffset'in işlendiğini de gösterir.
+	if err := c.close(); err != nil {
+		return fmt.Errorf("stream offset store error: %w", err)
+	}
+
+	log.Printf("[Stream] %s position for %s set to %s (offset %d)", queue, consumer, start, next)
+	return nil
+}
+
+// resolveStreamOffset kuyruğu x-stream-offset=arg ile kısa süreliğine tüketir ve gelen ilk
+// mesajın offset'ini döndürür.
+func resolveStreamOffset(queue string, arg interface{}) (int64, error) {
+	conn, err := dialAMQP(config.GetConfig().RabbitMQConnectionName + "-streamctl")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("channel error: %w", err)
+	}
+	if err := ch.Qos(1, 0, false); err != nil {
+		return 0, fmt.Errorf("qos error: %w", err)
+	}
+	msgs, err := ch.Consume(queue, "", false, false, false, false, amqp.Table{"x-stream-offset": arg})
+	if err != nil {
+		return 0, fmt.Errorf("consume error: %w", err)
+	}
+
+	select {
+	case d, ok := <-msgs:
+		if !ok {
+			return 0, fmt.Errorf("stream %s closed while resolving offset", queue)
+		}
+		off, ok := d.Headers["x-stream-offset"].(int64)
+		if !ok {
+			return 0, fmt.Errorf("delivery from %s has no x-stream-offset header", queue)
+		}
+		return off, nil
+	case <-time.After(streamIOTimeout):
+		return 0, fmt.Errorf("no message in %s at %v", queue, arg)
+	}
+}
@@ -0,0 +1,386 @@
+// internal/logfetcher/streamproto.go
+
+package logfetcher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+	"tedalogger-logfetcher/config"
+)
+
+// RabbitMQ stream protokolü komutları ve yanıt kodları. Yalnızca offset saklamak için
+// gerekenler tanımlıdır; tüketim AMQP ile yapılır.
+const (
+	streamCmdStoreOffset    uint16 = 0x000a
+	streamCmdQueryOffset    uint16 = 0x000b
+	streamCmdPeerProperties uint16 = 0x0011
+	streamCmdSaslHandshake  uint16 = 0x0012
+	streamCmdSaslAuth       uint16 = 0x0013
+	streamCmdTune           uint16 = 0x0014
+	streamCmdOpen           uint16 = 0x0015
+	streamCmdClose          uint16 = 0x0016
+	streamResponseFlag      uint16 = 0x8000
+
+	streamCodeOK       uint16 = 0x01
+	streamCodeNoOffset uint16 = 0x13
+
+	streamProtocolVersion uint16 = 1
+	streamMaxFrame               = 1 << 20
+	streamIOTimeout              = 10 * time.Second
+)
+
+// streamCodeError broker'ın OK dışındaki yanıt kodudur.
+type streamCodeError struct {
+	cmd  uint16
+	code uint16
+}
+
+func (e *streamCodeError) Error() string {
+	return fmt.Sprintf("stream command 0x%04x failed with response code 0x%02x", e.cmd, e.code)
+}
+
+// streamConn stream protokolü bağlantısıdır (varsayılan port 5552, TLS ile 5551). Heartbeat
+// kapatılarak açılır; istekler sıralıdır ve tek goroutine'den yapılmalıdır.
+type streamConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	corr uint32
+}
+
+// streamFrame bir isteğin gövdesini protokolün büyük-endian kodlamasıyla oluşturur.
+type streamFrame struct{ bytes.Buffer }
+
+func (f *streamFrame) u16(v uint16) { binary.Write(&f.Buffer, binary.BigEndian, v) }
+func (f *streamFrame) u32(v uint32) { binary.Write(&f.Buffer, binary.BigEndian, v) }
+func (f *streamFrame) u64(v uint64) { binary.Write(&f.Buffer, binary.BigEndian, v) }
+
+func (f *streamFrame) str(s string) {
+	f.u16(uint16(len(s)))
+	f.WriteString(s)
+}
+
+func (f *streamFrame) bytes(b []byte) {
+	f.u32(uint32(len(b)))
+	f.Write(b)
+}
+
+// streamReader yanıt gövdesini okur; ilk hatadan sonra sıfır değer döndürür.
+type streamReader struct {
+	b   []byte
+	err error
+}
+
+func (r *streamReader) take(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *streamReader) u16() uint16 { return binary.BigEndian.Uint16(r.take(2)) }
+func (r *streamReader) u32() uint32 { return binary.BigEndian.Uint32(r.take(4)) }
+func (r *streamReader) u64() uint64 { return binary.BigEndian.Uint64(r.take(8)) }
+
+func (r *streamReader) str() string {
+	n := int(int16(r.u16()))
+	if n < 0 {
+		return ""
+	}
+	return string(r.take(n))
+}
+
+// streamEndpoint stream protokolü adresi ve kimlik bilgileridir.
+type streamEndpoint struct {
+	addr     string
+	host     string
+	user     string
+	password string
+	vhost    string
+	tls      bool
+}
+
+// parseStreamEndpoint RABBITMQ_STREAM_URL'i (rabbitmq-stream:// ya da rabbitmq-stream+tls://)
+// çözer. Boşsa adres RABBITMQ_URL'den türetilir: aynı sunucu, kullanıcı ve vhost; port 5552
+// (amqps için 5551).
+func parseStreamEndpoint(cfg *config.Config) (streamEndpoint, error) {
+	if cfg.RabbitMQStreamURL == "" {
+		uri, err := amqp.ParseURI(cfg.RabbitMQURL)
+		if err != nil {
+			return streamEndpoint{}, fmt.Errorf("invalid RABBITMQ_URL: %w", err)
+		}
+		ep := streamEndpoint{host: uri.Host, user: uri.Username, password: uri.Password, vhost: uri.Vhost}
+		port := 5552
+		if uri.Scheme == "amqps" {
+			ep.tls, port = true, 5551
+		}
+		ep.addr = net.JoinHostPort(uri.Host, strconv.Itoa(port))
+		return ep, nil
+	}
+
+	u, err := url.Parse(cfg.RabbitMQStreamURL)
+	if err != nil {
+		return streamEndpoint{}, fmt.Errorf("invalid RABBITMQ_STREAM_URL: %w", err)
+	}
+	ep := streamEndpoint{host: u.Hostname(), user: "guest", password: "guest", vhost: "/"}
+	port := "5552"
+	switch u.Scheme {
+	case "rabbitmq-stream":
+	case "rabbitmq-stream+tls":
+		ep.tls, port = true, "5551"
+	default:
+		return streamEndpoint{}, fmt.Errorf("invalid RABBITMQ_STREAM_URL scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	ep.addr = net.JoinHostPort(ep.host, port)
+	if u.User != nil {
+		ep.user = u.User.Username()
+		ep.password, _ = u.User.Password()
+	}
+	if v := strings.TrimPrefix(u.Path, "/"); v != "" {
+		ep.vhost = v
+	}
+	return ep, nil
+}
+
+// dialStream stream protokolüyle bağlanır, PLAIN ile kimlik doğrular ve vhost'u açar.
+func dialStream(cfg *config.Config, connectionName string) (*streamConn, error) {
+	ep, err := parseStreamEndpoint(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: streamIOTimeout}
+	var conn net.Conn
+	if ep.tls {
+		tc, err := amqpTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if tc == nil {
+			tc = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		if tc.ServerName == "" {
+			tc = tc.Clone()
+			tc.ServerName = ep.host
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", ep.addr, tc)
+		if err != nil {
+			return nil, fmt.Errorf("RabbitMQ stream dial error: %w", err)
+		}
+	} else {
+		conn, err = dialer.Dial("tcp", ep.addr)
+		if err != nil {
+			return nil, fmt.Errorf("RabbitMQ stream dial error: %w", err)
+		}
+	}
+
+	c := newStreamConn(conn)
+	if err := c.open(ep, connectionName); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("RabbitMQ stream open error (%s): %w", ep.addr, err)
+	}
+	return c, nil
+}
+
+func newStreamConn(conn net.Conn) *streamConn {
+	return &streamConn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// open bağlantı el sıkışmasını yapar: PeerProperties, SaslHandshake, SaslAuthenticate,
+// sunucunun Tune'una heartbeat kapalı yanıt ve Open.
+func (c *streamConn) open(ep streamEndpoint, connectionName string) error {
+	if _, err := c.request(streamCmdPeerProperties, func(f *streamFrame) {
+		f.u32(2)
+		f.str("connection_name")
+		f.str(connectionName)
+		f.str("product")
+		f.str("tedalogger-logfetcher")
+	}); err != nil {
+		return err
+	}
+
+	body, err := c.request(streamCmdSaslHandshake, nil)
+	if err != nil {
+		return err
+	}
+	r := &streamReader{b: body}
+	plain := false
+	for n := r.u32(); n > 0 && r.err == nil; n-- {
+		if r.str() == "PLAIN" {
+			plain = true
+		}
+	}
+	if !plain {
+		return fmt.Errorf("broker does not offer SASL PLAIN")
+	}
+	if _, err := c.request(streamCmdSaslAuth, func(f *streamFrame) {
+		f.str("PLAIN")
+		f.bytes([]byte("\x00" + ep.user + "\x00" + ep.password))
+	}); err != nil {
+		return err
+	}
+
+	// Kimlik doğrulamadan sonra sunucu Tune gönderir; aynı frame boyutunu ve heartbeat'in
+	// kapalı olduğunu bildiririz. Bağlantı commit aralıklarında boşta kalır.
+	for {
+		key, body, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		if key != streamCmdTune {
+			continue
+		}
+		r := &streamReader{b: body}
+		frameMax := r.u32()
+		if r.err != nil {
+			return fmt.Errorf("malformed tune frame")
+		}
+		f := c.frame(streamCmdTune)
+		f.u32(frameMax)
+		f.u32(0)
+		if err := c.write(f); err != nil {
+			return err
+		}
+		break
+	}
+
+	_, err = c.request(streamCmdOpen, func(f *streamFrame) { f.str(ep.vhost) })
+	return err
+}
+
+// queryOffset tüketici adının stream için sakladığı offset'i döndürür; kayıt yoksa ok false.
+func (c *streamConn) queryOffset(reference, stream string) (offset int64, ok bool, err error) {
+	body, err := c.request(streamCmdQueryOffset, func(f *streamFrame) {
+		f.str(reference)
+		f.str(stream)
+	})
+	var ce *streamCodeError
+	if errors.As(err, &ce) && ce.code == streamCodeNoOffset {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	r := &streamReader{b: body}
+	off := r.u64()
+	if r.err != nil {
+		return 0, false, fmt.Errorf("malformed query offset response")
+	}
+	return int64(off), true, nil
+}
+
+// storeOffset offset'i broker'a yazar. Komutun yanıtı yoktur; hata ancak bağlantı
+// kapanınca ya da bir sonraki istekte görünür.
+func (c *streamConn) storeOffset(reference, stream string, offset int64) error {
+	f := c.frame(streamCmdStoreOffset)
+	f.str(reference)
+	f.str(stream)
+	f.u64(uint64(offset))
+	return c.write(f)
+}
+
+// close bağlantıyı Close komutuyla kapatır. Yanıt beklendiği için önceki storeOffset'lerin
+// broker tarafından işlendiği de bilinir.
+func (c *streamConn) close() error {
+	_, err := c.request(streamCmdClose, func(f *streamFrame) {
+		f.u16(streamCodeOK)
+		f.str("")
+	})
+	c.conn.Close()
+	return err
+}
+
+func (c *streamConn) frame(key uint16) *streamFrame {
+	f := &streamFrame{}
+	f.u16(key)
+	f.u16(streamProtocolVersion)
+	return f
+}
+
+func (c *streamConn) write(f *streamFrame) error {
+	c.conn.SetWriteDeadline(time.Now().Add(streamIOTimeout))
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(f.Len()))
+	if _, err := c.conn.Write(append(size[:], f.Bytes()...)); err != nil {
+		return fmt.Errorf("stream write error: %w", err)
+	}
+	return nil
+}
+
+// readFrame bir frame okur ve anahtarla sürümden sonraki gövdeyi döndürür.
+func (c *streamConn) readFrame() (key uint16, body []byte, err error) {
+	c.conn.SetReadDeadline(time.Now().Add(streamIOTimeout))
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return 0, nil, fmt.Errorf("stream read error: %w", err)
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > streamMaxFrame {
+		return 0, nil, fmt.Errorf("stream frame size %d out of range", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return 0, nil, fmt.Errorf("stream read error: %w", err)
+	}
+	return binary.BigEndian.Uint16(buf), buf[4:], nil
+}
+
+// request korelasyon kimlikli bir komut gönderir ve yanıtının koddan sonraki gövdesini
+// döndürür. Arada gelen heartbeat'ler atlanır; sunucu bağlantıyı kapatırsa hata döner.
+func (c *streamConn) request(key uint16, build func(*streamFrame)) ([]byte, error) {
+	c.corr++
+	corr := c.corr
+	f := c.frame(key)
+	f.u32(corr)
+	if build != nil {
+		build(f)
+	}
+	if err := c.write(f); err != nil {
+		return nil, err
+	}
+
+	for {
+		k, body, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if k == streamCmdClose && key != streamCmdClose {
+			r := &streamReader{b: body}
+			r.u32()
+			code := r.u16()
+			return nil, fmt.Errorf("broker closed stream connection (code 0x%02x): %s", code, r.str())
+		}
+		if k != key|streamResponseFlag {
+			continue
+		}
+		r := &streamReader{b: body}
+		if r.u32() != corr {
+			continue
+		}
+		code := r.u16()
+		if r.err != nil {
+			return nil, fmt.Errorf("malformed response to 0x%04x", key)
+		}
+		if code != streamCodeOK {
+			return nil, &streamCodeError{cmd: key, code: code}
+		}
+		return r.b, nil
+	}
+}
@@ -0,0 +1,207 @@
+package logfetcher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"tedalogger-logfetcher/config"
+)
+
+// fakeStreamBroker stream protokolünün offset komutlarını bellekte yanıtlar.
+type fakeStreamBroker struct {
+	mu      sync.Mutex
+	offsets map[string]int64
+	auth    string
+	vhost   string
+}
+
+func (b *fakeStreamBroker) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(key uint16, build func(f *streamFrame)) {
+		f := &streamFrame{}
+		f.u16(key)
+		f.u16(1)
+		build(f)
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(f.Len()))
+		conn.Write(append(size[:], f.Bytes()...))
+	}
+	respond := func(key uint16, corr uint32, code uint16, build func(f *streamFrame)) {
+		send(key|streamResponseFlag, func(f *streamFrame) {
+			f.u32(corr)
+			f.u16(code)
+			if build != nil {
+				build(f)
+			}
+		})
+	}
+
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		buf := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return
+		}
+		key := binary.BigEndian.Uint16(buf)
+		rd := &streamReader{b: buf[4:]}
+		switch key {
+		case streamCmdTune:
+			continue
+		case streamCmdStoreOffset:
+			ref, stream, off := rd.str(), rd.str(), rd.u64()
+			b.mu.Lock()
+			b.offsets[ref+"/"+stream] = int64(off)
+			b.mu.Unlock()
+			continue
+		}
+
+		corr := rd.u32()
+		switch key {
+		case streamCmdPeerProperties:
+			respond(key, corr, streamCodeOK, func(f *streamFrame) { f.u32(0) })
+		case streamCmdSaslHandshake:
+			respond(key, corr, streamCodeOK, func(f *streamFrame) {
+				f.u32(2)
+				f.str("AMQPLAIN")
+				f.str("PLAIN")
+			})
+		case streamCmdSaslAuth:
+			rd.str()
+			n := rd.u32()
+			b.auth = string(rd.take(int(n)))
+			respond(key, corr, streamCodeOK, nil)
+			// Sunucu heartbeat önerir; istemci kapalı olarak yanıtlar.
+			send(streamCmdTune, func(f *streamFrame) {
+				f.u32(1 << 20)
+				f.u32(60)
+			})
+		case streamCmdOpen:
+			b.vhost = rd.str()
+			respond(key, corr, streamCodeOK, func(f *streamFrame) { f.u32(0) })
+		case streamCmdQueryOffset:
+			ref, stream := rd.str(), rd.str()
+			b.mu.Lock()
+			off, ok := b.offsets[ref+"/"+stream]
+			b.mu.Unlock()
+			if !ok {
+				respond(key, corr, streamCodeNoOffset, func(f *streamFrame) { f.u64(0) })
+				continue
+			}
+			respond(key, corr, streamCodeOK, func(f *streamFrame) { f.u64(uint64(off)) })
+		case streamCmdClose:
+			respond(key, corr, streamCodeOK, nil)
+			return
+		default:
+			t.Errorf("unexpected command 0x%04x", key)
+			return
+		}
+	}
+}
+
+func (b *fakeStreamBroker) dial(t *testing.T) func() (*streamConn, error) {
+	return func() (*streamConn, error) {
+		client, server := net.Pipe()
+		go b.serve(t, server)
+		c := newStreamConn(client)
+		if err := c.open(streamEndpoint{user: "guest", password: "secret", vhost: "logs"}, "test"); err != nil {
+			client.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+func TestStreamConnOffsets(t *testing.T) {
+	b := &fakeStreamBroker{offsets: map[string]int64{}}
+	c, err := b.dial(t)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.auth != "\x00guest\x00secret" || b.vhost != "logs" {
+		t.Fatalf("auth=%q vhost=%q", b.auth, b.vhost)
+	}
+
+	if _, ok, err := c.queryOffset("tl", "q"); err != nil || ok {
+		t.Fatalf("query on empty store: ok=%v err=%v", ok, err)
+	}
+	if err := c.storeOffset("tl", "q", 42); err != nil {
+		t.Fatal(err)
+	}
+	if off, ok, err := c.queryOffset("tl", "q"); err != nil || !ok || off != 42 {
+		t.Fatalf("query = %d, %v, %v", off, ok, err)
+	}
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrokerOffsetStore(t *testing.T) {
+	b := &fakeStreamBroker{offsets: map[string]int64{}}
+	store := &brokerOffsetStore{dial: b.dial(t), queue: "q", consumer: "tl", stored: -1}
+	defer store.close()
+
+	if pos, err := store.load(); err != nil || pos != nil {
+		t.Fatalf("load on empty store = %+v, %v", pos, err)
+	}
+	if err := store.save(10); err != nil {
+		t.Fatal(err)
+	}
+
+	// StoreOffset'in yanıtı yok; sıradaki sorgu yazımın işlendiğini garanti eder.
+	if off, err := store.query(); err != nil || off != 10 {
+		t.Fatalf("query after save = %d, %v", off, err)
+	}
+
+	// streamctl konumu değiştirdi: bir sonraki yazım bunu fark eder.
+	b.mu.Lock()
+	b.offsets["tl/q"] = 3
+	b.mu.Unlock()
+	if err := store.save(11); !errors.Is(err, errStreamOffsetChanged) {
+		t.Fatalf("save after external change = %v", err)
+	}
+
+	// Yeniden başlayan oturum kayıtlı konumdan devam eder.
+	pos, err := store.load()
+	if err != nil || pos == nil || pos.NextOffset != 3 {
+		t.Fatalf("load = %+v, %v", pos, err)
+	}
+	if err := store.save(4); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseStreamEndpoint(t *testing.T) {
+	cases := []struct {
+		name               string
+		amqpURL, streamURL string
+		addr, user, vhost  string
+		tls                bool
+		wantErr            bool
+	}{
+		{"derived", "amqp://app:pw@rmq:5672/logs", "", "rmq:5552", "app", "logs", false, false},
+		{"derived tls", "amqps://app:pw@rmq/", "", "rmq:5551", "app", "/", true, false},
+		{"explicit", "amqp://app:pw@rmq/", "rabbitmq-stream://ops:x@stream:6000/feeds", "stream:6000", "ops", "feeds", false, false},
+		{"explicit tls", "", "rabbitmq-stream+tls://stream", "stream:5551", "guest", "/", true, false},
+		{"bad scheme", "", "amqp://stream", "", "", "", false, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ep, err := parseStreamEndpoint(&config.Config{RabbitMQURL: c.amqpURL, RabbitMQStreamURL: c.streamURL})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err = %v", err)
+			}
+			if err == nil && (ep.addr != c.addr || ep.user != c.user || ep.vhost != c.vhost || ep.tls != c.tls) {
+				t.Fatalf("endpoint = %+v", ep)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// internal/logfetcher/supervisor.go
+
+package logfetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+type consumerState string
+
+const (
+	stateStarting     consumerState = "starting"
+	stateRunning      consumerState = "running"
+	stateReconnecting consumerState = "reconnecting"
+	stateStopped      consumerState = "stopped"
+	stateFailed       consumerState = "failed"
+)
+
+// Bu süreden uzun ayakta kalan bir oturumdan sonra backoff baştan başlar.
+const backoffResetAfter = time.Minute
+
+type consumerHandle struct {
+	cancelFunc context.CancelFunc
+	queueName  string
+
+	// done, supervisor goroutine'i tamamen çıktığında kapanır.
+	done chan struct{}
+
+	mu      sync.Mutex
+	state   consumerState
+	lastErr error
+}
+
+func newConsumerHandle(queueName string, cancel context.CancelFunc) *consumerHandle {
+	return &consumerHandle{
+		cancelFunc: cancel,
+		queueName:  queueName,
+		done:       make(chan struct{}),
+		state:      stateStarting,
+	}
+}
+
+func (h *consumerHandle) setState(s consumerState, err error) {
+	h.mu.Lock()
+	h.state = s
+	h.lastErr = err
+	h.mu.Unlock()
+}
+
+func (h *consumerHandle) status() (consumerState, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state, h.lastErr
+}
+
+func (h *consumerHandle) exited() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// superviseConsumer kuyruğu context iptal edilene kadar tüketir. Bağlantı ya da kanal
+// koptuğunda exponential backoff + jitter ile yeniden bağlanır. Panik durumunda handle
+// failed olarak işaretlenir ve manager bir sonraki turda tüketiciyi yeniden başlatır.
+func superviseConsumer(ctx context.Context, h *consumerHandle, brand, nasIP string) {
+	defer close(h.done)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Consumer %s panicked: %v", h.queueName, r)
+			h.setState(stateFailed, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	attempt := 0
+	for {
+		h.setState(stateStarting, nil)
+		started := time.Now()
+
+		err := startConsumer(ctx, h.queueName, brand, nasIP, func() {
+			h.setState(stateRunning, nil)
+		})
+		if ctx.Err() != nil {
+			h.setState(stateStopped, nil)
+			return
+		}
+
+		if time.Since(started) > backoffResetAfter {
+			attempt = 0
+		}
+		delay := backoffDelay(attempt)
+		attempt++
+
+		incCounter("consumer_reconnects", 1)
+		h.setState(stateReconnecting, err)
+		log.Printf("Consumer %s stopped: %v; reconnecting in %s", h.queueName, err, delay.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			h.setState(stateStopped, nil)
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoffDelay RABBITMQ_RECONNECT_MIN/MAX ile expBackoff'tur.
+func backoffDelay(attempt int) time.Duration {
+	cfg := config.GetConfig()
+	return expBackoff(attempt, cfg.RabbitMQReconnectMin, cfg.RabbitMQReconnectMax)
+}
+
+// expBackoff min*2^attempt değerini max ile sınırlar ve [d/2, d) aralığında jitter uygular.
+func expBackoff(attempt int, min, max time.Duration) time.Duration {
+	d := min
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
@@ -0,0 +1,345 @@
+// internal/logfetcher/syslog_server.go
+
+package logfetcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	syslogIndexAttempts = 3
+	syslogUDPQueueSize  = 10000
+)
+
+// syslogSource syslog dinleyicilerinden gelen mesajları kaynak IP'si NAS adı olacak şekilde
+// verir. Syslog'da ack olmadığından geçici ES hataları birkaç kez yerinde denenir, sonra
+// mesaj düşürülüp sayılır.
+type syslogSource struct {
+	cfg         *config.Config
+	maxMessage  int
+	idleTimeout time.Duration
+	conns       chan struct{} // SYSLOG_MAX_CONNS semaforu
+	handle      func(SourceMessage)
+}
+
+// StartSyslogServer SYSLOG_UDP_ADDR, SYSLOG_TCP_ADDR ve SYSLOG_TLS_ADDR için dinleyici
+// açar. Hiçbiri ayarlı değilse hiçbir şey yapmaz.
+func StartSyslogServer() {
+	cfg := config.GetConfig()
+	if cfg.SyslogUDPAddr == "" && cfg.SyslogTCPAddr == "" && cfg.SyslogTLSAddr == "" {
+		return
+	}
+
+	ctx := context.Background()
+	proc := newNASProcessor(getSinkRouter(), "syslog", "[Syslog]", syslogIndexAttempts-1)
+	src := &syslogSource{
+		cfg:         cfg,
+		maxMessage:  cfg.SyslogMaxMessage,
+		idleTimeout: cfg.SyslogIdleTimeout,
+	}
+	if cfg.SyslogMaxConns > 0 {
+		src.conns = make(chan struct{}, cfg.SyslogMaxConns)
+	}
+	if err := src.Run(ctx, func(m SourceMessage) { proc.process(ctx, m) }); err != nil {
+		log.Printf("[Syslog] %v", err)
+	}
+}
+
+func (s *syslogSource) Name() string { return "syslog" }
+
+// Run dinleyicileri açar ve ctx iptal edilene kadar bekler.
+func (s *syslogSource) Run(ctx context.Context, handle func(SourceMessage)) error {
+	s.handle = handle
+	cfg := s.cfg
+
+	if cfg.SyslogUDPAddr != "" {
+		go func() {
+			if err := s.serveUDP(cfg.SyslogUDPAddr, cfg.SyslogWorkers); err != nil {
+				log.Printf("[Syslog] UDP listener error: %v", err)
+			}
+		}()
+	}
+	if cfg.SyslogTCPAddr != "" {
+		go func() {
+			ln, err := net.Listen("tcp", cfg.SyslogTCPAddr)
+			if err != nil {
+				log.Printf("[Syslog] TCP listen error: %v", err)
+				return
+			}
+			log.Printf("[Syslog] Listening on tcp %s", cfg.SyslogTCPAddr)
+			s.serveStream(ln)
+		}()
+	}
+	if cfg.SyslogTLSAddr != "" {
+		go func() {
+			tc, err := syslogTLSConfig(cfg)
+			if err != nil {
+				log.Printf("[Syslog] TLS config error: %v", err)
+				return
+			}
+			ln, err := tls.Listen("tcp", cfg.SyslogTLSAddr, tc)
+			if err != nil {
+				log.Printf("[Syslog] TLS listen error: %v", err)
+				return
+			}
+			log.Printf("[Syslog] Listening on tls %s", cfg.SyslogTLSAddr)
+			s.serveStream(ln)
+		}()
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func (s *syslogSource) serveUDP(addr string, workers int) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("[Syslog] Listening on udp %s", addr)
+
+	type datagram struct {
+		src string
+		msg []byte
+	}
+	queue := make(chan datagram, syslogUDPQueueSize)
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for dg := range queue {
+				s.emit(dg.src, dg.msg)
+			}
+		}()
+	}
+
+	buf := make([]byte, s.maxMessage)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		msg := make([]byte, n)
+		copy(msg, buf[:n])
+
+		select {
+		case queue <- datagram{src: hostOf(from), msg: msg}:
+		default:
+			incCounter("syslog_udp_dropped", 1)
+		}
+	}
+}
+
+func (s *syslogSource) serveStream(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("[Syslog] Accept error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if s.conns != nil {
+			select {
+			case s.conns <- struct{}{}:
+			default:
+				incCounter("syslog_conn_rejected", 1)
+				conn.Close()
+				continue
+			}
+		}
+		go func() {
+			s.handleConn(conn)
+			if s.conns != nil {
+				<-s.conns
+			}
+		}()
+	}
+}
+
+// handleConn RFC 6587 çerçevelerini okur: "LEN SP <PRI>" ile başlıyorsa octet-counting,
+// değilse satır sonu ile ayrılmış mesaj. Çerçeve tipi her mesajda yeniden belirlenir.
+// SYSLOG_IDLE_TIMEOUT içinde tam bir mesaj gelmezse bağlantı kapatılır. Mesajlar sırayla
+// işlendiği için yavaş ES göndericiyi yavaşlatır.
+func (s *syslogSource) handleConn(conn net.Conn) {
+	defer conn.Close()
+	src := hostOf(conn.RemoteAddr())
+	r := bufio.NewReaderSize(conn, 64*1024)
+
+	for {
+		if s.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
+		msg, err := readSyslogFrame(r, s.maxMessage)
+		if len(msg) > 0 {
+			s.emit(src, msg)
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("[Syslog] Connection %s closed: %v", src, err)
+			}
+			return
+		}
+	}
+}
+
+// syslogMaxLenDigits octet-counting uzunluğunun en fazla basamak sayısıdır.
+const syslogMaxLenDigits = 10
+
+// readSyslogFrame bir mesaj okur. maxMessage'ı aşan mesajın fazlası bellekte tutulmadan
+// atlanır ve mesaj kesilmiş olarak döner.
+func readSyslogFrame(r *bufio.Reader, maxMessage int) ([]byte, error) {
+	n, ok, err := peekOctetCount(r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		line, _, truncated, err := readBoundedLine(r, maxMessage)
+		if truncated {
+			incCounter("syslog_truncated", 1)
+		}
+		return bytes.TrimRight(line, "\r\x00"), err
+	}
+
+	if _, err := r.Discard(len(strconv.Itoa(n)) + 1); err != nil {
+		return nil, err
+	}
+	size := n
+	if size > maxMessage {
+		size = maxMessage
+		incCounter("syslog_truncated", 1)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	if _, err := r.Discard(n - size); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// peekOctetCount çerçevenin "LEN SP <" ile başlayıp başlamadığına bakar. Rakamla başlayan
+// satırlar (tarih, IP) yanlışlıkla uzunluk sayılmasın diye uzunluktan sonra syslog PRI
+// alanının gelmesi de aranır. Yalnızca karar için gereken kadar bayt beklenir.
+func peekOctetCount(r *bufio.Reader) (int, bool, error) {
+	for i := 0; ; i++ {
+		b, err := r.Peek(i + 1)
+		if err != nil {
+			if i > 0 && err == io.EOF {
+				return 0, false, nil
+			}
+			return 0, false, err
+		}
+		c := b[i]
+		switch {
+		case c >= '0' && c <= '9' && i < syslogMaxLenDigits:
+			if i == 0 && c == '0' {
+				return 0, false, nil
+			}
+			continue
+		case c == ' ' && i > 0:
+			next, err := r.Peek(i + 2)
+			if err != nil || next[i+1] != '<' {
+				return 0, false, nil
+			}
+			n, err := strconv.Atoi(string(b[:i]))
+			if err != nil {
+				return 0, false, nil
+			}
+			return n, true, nil
+		}
+		return 0, false, nil
+	}
+}
+
+// readBoundedLine '\n' ya da "\r\n" ile biten bir satır okur. Satırın en fazla max baytı
+// döner, fazlası okunup atılır; böylece satır sonu göndermeyen bir kaynak belleği
+// dolduramaz. n satır sonu dahil tüketilen bayt sayısıdır. Satır sonundan önce okuma
+// biterse hata (io.EOF) ile o ana kadar okunan kısım döner.
+func readBoundedLine(r *bufio.Reader, max int) (line []byte, n int64, truncated bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		n += int64(len(chunk))
+		if err == nil {
+			chunk = bytes.TrimSuffix(chunk[:len(chunk)-1], []byte{'\r'})
+		}
+		if room := max - len(line); len(chunk) > room {
+			chunk = chunk[:room]
+			truncated = true
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, n, truncated, err
+		}
+	}
+}
+
+func (s *syslogSource) emit(src string, msg []byte) {
+	incCounter("syslog_received", 1)
+
+	s.handle(SourceMessage{
+		Body: msg,
+		Meta: LogMessage{
+			FromHost:     src,
+			TimeReported: time.Now().UTC().Format(time.RFC3339),
+		},
+		NAS: src,
+		Ack: func() {},
+		Nack: func(err error, retryable bool) {
+			if errors.Is(err, errUnparseable) {
+				return
+			}
+			incCounter("syslog_dropped", 1)
+			log.Printf("ES index error, dropping syslog message (syslog=%s): %v", src, err)
+		},
+	})
+}
+
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// syslogTLSConfig RFC 5425 dinleyicisi için sunucu sertifikasını yükler. SYSLOG_TLS_CA
+// verilirse istemci sertifikası zorunlu tutulur.
+func syslogTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.SyslogTLSCert, cfg.SyslogTLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("server certificate error: %w", err)
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.SyslogTLSCA != "" {
+		pem, err := os.ReadFile(cfg.SyslogTLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("client CA read error: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA file has no valid certificates: %s", cfg.SyslogTLSCA)
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
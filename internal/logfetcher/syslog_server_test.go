@@ -0,0 +1,68 @@
+package logfetcher
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadSyslogFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		max   int
+		want  []string
+	}{
+		{"newline framed", "<13>a\n<13>b\r\n", 64, []string{"<13>a", "<13>b"}},
+		{"octet counted", "5 <13>a6 <13>bc", 64, []string{"<13>a", "<13>bc"}},
+		{"mixed", "5 <13>a<13>b\n", 64, []string{"<13>a", "<13>b"}},
+		{"newline line starting with digits", "2024-01-01 msg\n10.0.0.1 msg\n", 64, []string{"2024-01-01 msg", "10.0.0.1 msg"}},
+		{"digits and space without pri", "12 blocked requests\n", 64, []string{"12 blocked requests"}},
+		{"leading zero is not a length", "05 <13>abcd\n", 64, []string{"05 <13>abcd"}},
+		{"long line truncated", strings.Repeat("x", 100) + "\n<13>next\n", 10, []string{strings.Repeat("x", 10), "<13>next"}},
+		{"long octet frame truncated", "12 <13>abcdefgh<13>z\n", 5, []string{"<13>a", "<13>z"}},
+		{"last line without newline", "<13>a\n<13>b", 64, []string{"<13>a", "<13>b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(tt.input), 16)
+			var got []string
+			for {
+				msg, err := readSyslogFrame(r, tt.max)
+				if len(msg) > 0 {
+					got = append(got, string(msg))
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReadBoundedLine satır sonu gelmeyen akışta belleğin max ile sınırlı kaldığını ve
+// tüketilen bayt sayısının doğru olduğunu sınar.
+func TestReadBoundedLine(t *testing.T) {
+	r := bufio.NewReaderSize(strings.NewReader(strings.Repeat("y", 1<<20)), 16)
+	line, n, truncated, err := readBoundedLine(r, 32)
+	if err != io.EOF || !truncated || len(line) != 32 || n != 1<<20 {
+		t.Fatalf("len=%d n=%d truncated=%v err=%v", len(line), n, truncated, err)
+	}
+
+	r = bufio.NewReaderSize(strings.NewReader("abc\r\ndef\n"), 16)
+	line, n, truncated, err = readBoundedLine(r, 3)
+	if err != nil || string(line) != "abc" || n != 5 || truncated {
+		t.Fatalf("line=%q n=%d truncated=%v err=%v", line, n, truncated, err)
+	}
+	line, n, truncated, err = readBoundedLine(r, 3)
+	if err != nil || string(line) != "def" || n != 4 || truncated {
+		t.Fatalf("line=%q n=%d truncated=%v err=%v", line, n, truncated, err)
+	}
+}
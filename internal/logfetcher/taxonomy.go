@@ -0,0 +1,127 @@
+// internal/logfetcher/taxonomy.go
+
+package logfetcher
+
+import (
+	"net"
+	"strings"
+)
+
+// taxonomyVersion eşleme tablolarında anlam değiştiren her düzenlemede artırılmalıdır;
+// böylece farklı sürümlerle normalize edilmiş belgeler birbirinden ayrılabilir.
+const taxonomyVersion = "1"
+
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomeUnknown = "unknown"
+)
+
+type actionMapping struct {
+	Outcome string
+	Action  string
+}
+
+// actionTaxonomy marka bazında ham action değerlerini ortak event.outcome/event.action
+// değerlerine eşler. Tabloda olmayan değerler outcome=unknown ile ham haliyle geçer.
+var actionTaxonomy = map[string]map[string]actionMapping{
+	"forti": {
+		"accept":      {outcomeSuccess, "allowed"},
+		"passthrough": {outcomeSuccess, "allowed"},
+		"pass":        {outcomeSuccess, "allowed"},
+		"permit":      {outcomeSuccess, "allowed"},
+		"monitor":     {outcomeSuccess, "monitored"},
+		"redirect":    {outcomeSuccess, "redirected"},
+		"close":       {outcomeSuccess, "session-closed"},
+		"timeout":     {outcomeSuccess, "session-timeout"},
+		"client-rst":  {outcomeSuccess, "session-reset"},
+		"server-rst":  {outcomeSuccess, "session-reset"},
+		"deny":        {outcomeFailure, "blocked"},
+		"block":       {outcomeFailure, "blocked"},
+		"blocked":     {outcomeFailure, "blocked"},
+		"dropped":     {outcomeFailure, "blocked"},
+		"reset":       {outcomeFailure, "blocked"},
+	},
+	"ruijie": {
+		"1": {outcomeSuccess, "allowed"},
+		"0": {outcomeFailure, "blocked"},
+		"2": {outcomeFailure, "blocked"},
+	},
+}
+
+var directionTaxonomy = map[string]string{
+	"outgoing": "outbound",
+	"outbound": "outbound",
+	"incoming": "inbound",
+	"inbound":  "inbound",
+	"internal": "internal",
+	"external": "external",
+}
+
+// IANA protokol numaraları; yalnızca firewall loglarında karşılaşılanlar.
+var protocolNames = map[string]string{
+	"1":   "icmp",
+	"2":   "igmp",
+	"6":   "tcp",
+	"17":  "udp",
+	"41":  "ipv6",
+	"47":  "gre",
+	"50":  "esp",
+	"51":  "ah",
+	"58":  "ipv6-icmp",
+	"89":  "ospf",
+	"132": "sctp",
+}
+
+func normalizeTaxonomy(pl *ParsedLog) {
+	pl.TaxonomyVersion = taxonomyVersion
+
+	if pl.ActionRaw != "" {
+		raw := strings.ToLower(strings.TrimSpace(pl.ActionRaw))
+		if m, ok := actionTaxonomy[pl.Brand][raw]; ok {
+			pl.EventOutcome = m.Outcome
+			pl.EventAction = m.Action
+		} else {
+			pl.EventOutcome = outcomeUnknown
+			pl.EventAction = raw
+		}
+	}
+
+	if pl.ProtoRaw != "" {
+		raw := strings.ToLower(strings.TrimSpace(pl.ProtoRaw))
+		if name, ok := protocolNames[raw]; ok {
+			pl.NetworkProtocol = name
+		} else {
+			pl.NetworkProtocol = raw
+		}
+	}
+
+	if dir, ok := directionTaxonomy[strings.ToLower(pl.DirectionRaw)]; ok {
+		pl.NetworkDirection = dir
+	} else {
+		pl.NetworkDirection = directionFromIPs(pl.SrcIP, pl.DstIP)
+	}
+}
+
+// directionFromIPs cihaz yön bilgisi göndermediğinde yönü adres tiplerinden çıkarır.
+func directionFromIPs(src, dst string) string {
+	s, d := net.ParseIP(src), net.ParseIP(dst)
+	if s == nil || d == nil {
+		return ""
+	}
+	sInt, dInt := isInternalIP(s), isInternalIP(d)
+	switch {
+	case sInt && dInt:
+		return "internal"
+	case sInt && !dInt:
+		return "outbound"
+	case !sInt && dInt:
+		return "inbound"
+	default:
+		return "external"
+	}
+}
+
+func isInternalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
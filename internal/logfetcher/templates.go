@@ -0,0 +1,691 @@
+// internal/logfetcher/templates.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"tedalogger-logfetcher/config"
+)
+
+// templateVersion mapping'ler ya da ayarlar uyumsuz biçimde değiştiğinde artırılır. Kümede
+// daha yüksek sürüm varsa (yeni bir logfetcher kurmuş) üzerine yazılmaz.
+const templateVersion = 1
+
+const (
+	templateManagedBy = "tedalogger-logfetcher"
+
+	settingsComponent = "tedalogger-urlfilter-settings"
+	mappingsComponent = "tedalogger-urlfilter-mappings-" // + şema
+)
+
+// Aynı önceliğe sahip ve desenleri kesişen şablonları ES reddeder; bu yüzden her grup ayrı
+// önceliktedir. Yerleşik logs-*-* şablonu 100 olduğu için varsayılan onun üstündedir.
+// ES_INDEX_SCHEMAS kuralları sıralarına göre prioritySchemas+maxIndexSchemas ile
+// prioritySchemas+1 arasında öncelik alır.
+const (
+	priorityDefault      = 150
+	prioritySchemas      = 200
+	priorityDataStream   = 260
+	priorityECSMigration = 300
+)
+
+// maxIndexSchemas ES_INDEX_SCHEMAS'ta dikkate alınan en fazla kural sayısıdır; öncelikler
+// data stream şablonununkine ulaşmasın diye sınırlıdır.
+const maxIndexSchemas = 50
+
+// schemaTemplatePrefix ES_INDEX_SCHEMAS kurallarının şablon adlarının önekidir.
+const schemaTemplatePrefix = "tedalogger-urlfilter-schema-"
+
+// managedMeta kurduğumuz nesnelerin _meta alanıdır. Hash gövdenin özetidir; yapılandırma
+// (desen, ILM süreleri) değiştiğinde de nesne yeniden yazılır.
+type managedMeta struct {
+	ManagedBy string `json:"managed_by"`
+	Version   int    `json:"version"`
+	Hash      string `json:"hash"`
+}
+
+// InstallTemplates ILM politikasını, component template'leri ve index template'leri kurar.
+// Kümedeki nesne aynıysa dokunulmaz; böylece her başlangıçta güvenle çağrılabilir.
+// Şablonlar yalnızca yeni açılan indekslere uygulanır; mevcut günün indeksi değişmez.
+func InstallTemplates(es *elasticsearch.Client) error {
+	cfg := config.GetConfig()
+	if err := installManaged("ILM policy", cfg.ESILMPolicy, ilmPolicyBody(cfg, false), ilmPolicyAPI(es.API)); err != nil {
+		return err
+	}
+	if err := installManaged("ILM policy", rolloverPolicyName(cfg), ilmPolicyBody(cfg, true), ilmPolicyAPI(es.API)); err != nil {
+		return err
+	}
+	daily, err := ownedDailyPatterns(es.API, cfg)
+	if err != nil {
+		return err
+	}
+	return installIndexTemplates(es.API, cfg, daily, templateFlavor{lifecycle: true, wildcard: true})
+}
+
+// defaultTemplateName günlük indekslerin ve karantinanın şablonudur.
+const defaultTemplateName = "tedalogger-urlfilter"
+
+// nasPatternSuffix NAS'tan türetilen günlük indeks deseninin sonudur (<nas>-GG-AA-YYYY).
+const nasPatternSuffix = "-*-*-20*"
+
+var (
+	templateNASMu sync.Mutex
+	templateNAS   = make(map[string]bool) // desenleri şablonlara eklenen NAS'lar
+)
+
+func nasIndexPattern(nasName string) string {
+	return strings.ReplaceAll(nasName, ".", "_") + nasPatternSuffix
+}
+
+// addTemplateNAS NAS'ları şablon desenlerine ekler; yeni NAS varsa true döner.
+func addTemplateNAS(list []NAS) bool {
+	templateNASMu.Lock()
+	defer templateNASMu.Unlock()
+	added := false
+	for _, n := range list {
+		if n.Nasname != "" && !templateNAS[n.Nasname] {
+			templateNAS[n.Nasname] = true
+			added = true
+		}
+	}
+	return added
+}
+
+// refreshNASTemplates listedeki yeni NAS'ların günlük indeks desenlerini, registry'ye
+// girip belgeleri yazılmadan önce şablonlara ekler.
+func refreshNASTemplates(list []NAS) {
+	cfg := config.GetConfig()
+	if !addTemplateNAS(list) || !cfg.ESTemplatesInstall || len(cfg.ESTemplatePatterns) > 0 {
+		return
+	}
+	getSinkRouter().reinstallTemplates()
+}
+
+// ownedDailyPatterns bu servisin yazdığı günlük indekslerin desenleridir. ES_TEMPLATE_PATTERNS
+// verilmemişse manager'ın NAS listesinden "<nas>-*-*-20*" olarak türetilir ve kümedeki
+// şablonun NAS desenleriyle birleştirilir; böylece NAS listesinin bir kısmını bilen backfill
+// ya da başka bir örnek, diğer NAS'ların desenlerini silmez. Listeden çıkan NAS'ın deseni
+// kalır; indeksleri politikaya bağlı kalmaya devam eder.
+func ownedDailyPatterns(es *esapi.API, cfg *config.Config) ([]string, error) {
+	if len(cfg.ESTemplatePatterns) > 0 {
+		return cfg.ESTemplatePatterns, nil
+	}
+	set := make(map[string]bool)
+	templateNASMu.Lock()
+	for n := range templateNAS {
+		set[nasIndexPattern(n)] = true
+	}
+	templateNASMu.Unlock()
+
+	existing, err := indexTemplatePatterns(es, defaultTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if strings.HasSuffix(p, nasPatternSuffix) {
+			set[p] = true
+		}
+	}
+
+	out := make([]string, 0, len(set))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// indexTemplatePatterns kümedeki index template'in desenlerini döndürür; yoksa nil.
+func indexTemplatePatterns(es *esapi.API, name string) ([]string, error) {
+	res, err := es.Indices.GetIndexTemplate(es.Indices.GetIndexTemplate.WithName(name))
+	if err != nil {
+		return nil, fmt.Errorf("get index template %s: %w", name, err)
+	}
+	data, err := readBody(res)
+	if err != nil {
+		return nil, fmt.Errorf("get index template %s: %w", name, err)
+	}
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get index template %s: %s", name, res.String())
+	}
+	var r struct {
+		IndexTemplates []struct {
+			IndexTemplate struct {
+				IndexPatterns []string `json:"index_patterns"`
+			} `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse index template %s: %w", name, err)
+	}
+	if len(r.IndexTemplates) == 0 {
+		return nil, nil
+	}
+	return r.IndexTemplates[0].IndexTemplate.IndexPatterns, nil
+}
+
+// templateFlavor kümeler arası şablon farklarıdır.
+type templateFlavor struct {
+	// lifecycle: indekse ILM politikası index.lifecycle.name ayarıyla bağlanır. OpenSearch'te
+	// ISM politikası indeksleri kendi ism_template desenleriyle yakalar.
+	lifecycle bool
+	// wildcard alan türü yoksa URL alanları keyword olarak eşlenir.
+	wildcard bool
+}
+
+// installIndexTemplates component template'leri ve index template'leri kurar.
+func installIndexTemplates(es *esapi.API, cfg *config.Config, daily []string, flavor templateFlavor) error {
+	if err := installManaged("component template", settingsComponent, settingsComponentBody(cfg, flavor), componentTemplateAPI(es)); err != nil {
+		return err
+	}
+	for _, schema := range []string{schemaLegacy, schemaECS} {
+		name := mappingsComponent + schema
+		mappings := mappingsFor(schema)
+		if !flavor.wildcard {
+			mappings = withoutWildcard(mappings)
+		}
+		body := map[string]any{"template": map[string]any{"mappings": mappings}}
+		if err := installManaged("component template", name, body, componentTemplateAPI(es)); err != nil {
+			return err
+		}
+	}
+
+	if n := len(cfg.IndexSchemas) - len(indexSchemaRules(cfg)); n > 0 {
+		log.Printf("[Templates] %d ES_INDEX_SCHEMAS rule(s) skipped: only * wildcards and at most %d rules are supported", n, maxIndexSchemas)
+	}
+	specs := indexTemplates(cfg, daily)
+	for _, t := range specs {
+		if len(t.patterns) == 0 {
+			// Artık kullanılmayan şablon kalmasın.
+			if err := deleteIndexTemplate(es, t.name); err != nil {
+				return err
+			}
+			continue
+		}
+		body := map[string]any{
+			"index_patterns": t.patterns,
+			"priority":       t.priority,
+			"composed_of":    []string{settingsComponent, mappingsComponent + t.schema},
+		}
+		if t.dataStream {
+			body["data_stream"] = map[string]any{}
+			if flavor.lifecycle {
+				// Data stream arka indeksleri rollover'lı politikayı kullanır; şablonun kendi
+				// ayarları component'lerdekini ezer.
+				body["template"] = map[string]any{
+					"settings": map[string]any{"index.lifecycle.name": rolloverPolicyName(cfg)},
+				}
+			}
+		}
+		if err := installManaged("index template", t.name, body, indexTemplateAPI(es)); err != nil {
+			return err
+		}
+	}
+	return deleteStaleSchemaTemplates(es, specs)
+}
+
+type indexTemplateSpec struct {
+	name       string
+	schema     string
+	priority   int
+	patterns   []string
+	dataStream bool
+}
+
+// indexTemplates varsayılan şablonu ve ES_INDEX_SCHEMAS ile şeması ezilen desenlerin
+// şablonlarını döndürür. Şablonlar ILM/ISM politikasını (salt okunur yapma, silme) da
+// bağladığından yalnızca bu servisin yazdığı indekslere uymalıdır: varsayılan şablon
+// ownedDailyPatterns'in günlük desenlerini ve karantina indeksini alır. Bu desenlerden
+// ECS'ye taşınmış "-ecs" indeksleri her zaman ECS mapping'i alır.
+func indexTemplates(cfg *config.Config, daily []string) []indexTemplateSpec {
+	patterns := append([]string(nil), daily...)
+	if cfg.IndexQuarantine != "" {
+		patterns = append(patterns, cfg.IndexQuarantine)
+	}
+
+	specs := []indexTemplateSpec{
+		{defaultTemplateName, normalizeSchemaName(cfg.OutputSchema), priorityDefault, patterns, false},
+		// Kurallar tek tek şablon almadan önceki grup şablonları kaldırılır.
+		{"tedalogger-urlfilter-legacy", schemaLegacy, 0, nil, false},
+		{"tedalogger-urlfilter-ecs", schemaECS, 0, nil, false},
+	}
+
+	// Her kural kendi şablonunu alır; öncekinin önceliği yüksektir, böylece ES de
+	// schemaForIndex gibi ilk eşleşen kuralı uygular.
+	rules := indexSchemaRules(cfg)
+	migrated := make([]string, 0, len(daily)+len(rules))
+	for _, p := range daily {
+		migrated = append(migrated, p+"-ecs")
+	}
+	for i, rule := range rules {
+		specs = append(specs, indexTemplateSpec{
+			name:     fmt.Sprintf("%s%d", schemaTemplatePrefix, i+1),
+			schema:   normalizeSchemaName(rule.Value),
+			priority: prioritySchemas + maxIndexSchemas - i,
+			patterns: []string{rule.Key},
+		})
+		migrated = append(migrated, rule.Key+"-ecs")
+	}
+
+	return append(specs,
+		indexTemplateSpec{"tedalogger-urlfilter-datastream", normalizeSchemaName(cfg.OutputSchema), priorityDataStream,
+			[]string{cfg.ESDataStreamPrefix + "*"}, true},
+		indexTemplateSpec{"tedalogger-urlfilter-ecs-migrated", schemaECS, priorityECSMigration, migrated, false},
+	)
+}
+
+// deleteStaleSchemaTemplates ES_INDEX_SCHEMAS'tan çıkarılmış kuralların şablonlarını siler.
+func deleteStaleSchemaTemplates(es *esapi.API, specs []indexTemplateSpec) error {
+	res, err := es.Indices.GetIndexTemplate(es.Indices.GetIndexTemplate.WithName(schemaTemplatePrefix + "*"))
+	if err != nil {
+		return fmt.Errorf("get index templates %s*: %w", schemaTemplatePrefix, err)
+	}
+	data, err := readBody(res)
+	if err != nil {
+		return fmt.Errorf("get index templates %s*: %w", schemaTemplatePrefix, err)
+	}
+	if res.StatusCode == 404 {
+		return nil
+	}
+	if res.IsError() {
+		return fmt.Errorf("get index templates %s*: %s", schemaTemplatePrefix, res.String())
+	}
+	var r struct {
+		IndexTemplates []struct {
+			Name string `json:"name"`
+		} `json:"index_templates"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("parse index templates %s*: %w", schemaTemplatePrefix, err)
+	}
+
+	keep := make(map[string]bool, len(specs))
+	for _, s := range specs {
+		keep[s.name] = true
+	}
+	for _, t := range r.IndexTemplates {
+		if !keep[t.Name] {
+			if err := deleteIndexTemplate(es, t.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func rolloverPolicyName(cfg *config.Config) string {
+	return cfg.ESILMPolicy + "-rollover"
+}
+
+// ilmPolicyBody günlük indeksler için rollover'sız, data stream'ler için rollover'lı
+// politikayı döndürür. Rollover'lı politikada fazların yaşı rollover anından sayılır.
+func ilmPolicyBody(cfg *config.Config, rollover bool) map[string]any {
+	hotActions := map[string]any{"set_priority": map[string]any{"priority": 100}}
+	if rollover {
+		r := map[string]any{}
+		if cfg.ESRolloverMaxSize != "" {
+			r["max_primary_shard_size"] = cfg.ESRolloverMaxSize
+		}
+		if cfg.ESRolloverMaxAge != "" {
+			r["max_age"] = cfg.ESRolloverMaxAge
+		}
+		if len(r) > 0 {
+			hotActions["rollover"] = r
+		}
+	}
+	phases := map[string]any{
+		"hot": map[string]any{
+			"min_age": "0ms",
+			"actions": hotActions,
+		},
+	}
+	if cfg.ESILMWarmAfter != "" {
+		phases["warm"] = map[string]any{
+			"min_age": cfg.ESILMWarmAfter,
+			"actions": map[string]any{
+				"set_priority": map[string]any{"priority": 50},
+				"forcemerge":   map[string]any{"max_num_segments": 1},
+				"readonly":     map[string]any{},
+			},
+		}
+	}
+	if cfg.ESILMDeleteAfter != "" {
+		phases["delete"] = map[string]any{
+			"min_age": cfg.ESILMDeleteAfter,
+			"actions": map[string]any{"delete": map[string]any{}},
+		}
+	}
+	return map[string]any{"policy": map[string]any{"phases": phases}}
+}
+
+func settingsComponentBody(cfg *config.Config, flavor templateFlavor) map[string]any {
+	settings := map[string]any{
+		"index.number_of_shards":   cfg.ESIndexShards,
+		"index.number_of_replicas": cfg.ESIndexReplicas,
+	}
+	if flavor.lifecycle {
+		settings["index.lifecycle.name"] = cfg.ESILMPolicy
+	}
+	return map[string]any{"template": map[string]any{"settings": settings}}
+}
+
+var (
+	mapKeyword  = map[string]any{"type": "keyword", "ignore_above": 1024}
+	mapIP       = map[string]any{"type": "ip", "ignore_malformed": true}
+	mapDate     = map[string]any{"type": "date"}
+	mapWildcard = map[string]any{"type": "wildcard"}
+	mapInteger  = map[string]any{"type": "integer"}
+	mapLong     = map[string]any{"type": "long"}
+	// mapStored _source'ta tutulur ama aranmaz; ham log yalnızca dışa aktarım için gerekir.
+	mapStored = map[string]any{"type": "text", "index": false}
+	// mapLongKeyword wildcard desteklemeyen kümelerde URL alanlarının yerine geçer.
+	mapLongKeyword = map[string]any{"type": "keyword", "ignore_above": 8191}
+)
+
+func object(props map[string]any) map[string]any {
+	return map[string]any{"properties": props}
+}
+
+// mappingsFor şemaya göre açık mapping'i döndürür. Listede olmayan metin alanları
+// analiz edilmeden keyword olarak eşlenir.
+func mappingsFor(schema string) map[string]any {
+	m := map[string]any{
+		"dynamic_templates": []any{
+			map[string]any{"strings_as_keyword": map[string]any{
+				"match_mapping_type": "string",
+				"mapping":            mapKeyword,
+			}},
+		},
+	}
+	if schema == schemaECS {
+		m["properties"] = ecsProperties()
+	} else {
+		m["properties"] = legacyProperties()
+	}
+	return m
+}
+
+func legacyProperties() map[string]any {
+	props := map[string]any{
+		"@timestamp":      mapDate, // yalnızca data stream modunda yazılır
+		"src_ip":          mapIP,
+		"dst_ip":          mapIP,
+		"url":             mapWildcard,
+		"timestamp":       mapDate,
+		"raw_message":     mapStored,
+		"raw_message_b64": mapStored,
+		"invalid_bytes":   mapInteger,
+	}
+	for _, f := range []string{
+		"brand", "src_port", "dst_port", "action", "from_host", "device_id", "url_category",
+		"src_mac", "policy_name", "user", "dev_id", "dev_name", "src_intf", "hostname",
+		"nas_name", "src_hostname", "asset_owner", "asset_department", "user_display_name",
+		"user_department", "user_groups", "raw_charset", "action_raw", "proto_raw",
+		"direction_raw", "event_outcome", "event_action", "network_direction",
+		"network_protocol", "taxonomy_version", "declared_brand", "detected_brand", "tags",
+	} {
+		props[f] = mapKeyword
+	}
+	return props
+}
+
+// withoutWildcard mapping'in wildcard alanlarını keyword'e çevrilmiş kopyasını döndürür.
+func withoutWildcard(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if child, ok := v.(map[string]any); ok {
+			if child["type"] == "wildcard" {
+				out[k] = mapLongKeyword
+			} else {
+				out[k] = withoutWildcard(child)
+			}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func ecsProperties() map[string]any {
+	return map[string]any{
+		"@timestamp": mapDate,
+		"message":    mapStored,
+		"tags":       mapKeyword,
+		"ecs":        object(map[string]any{"version": mapKeyword}),
+		"event": object(map[string]any{
+			"kind": mapKeyword, "category": mapKeyword, "action": mapKeyword, "outcome": mapKeyword,
+		}),
+		"source": object(map[string]any{
+			"ip": mapIP, "port": mapLong, "mac": mapKeyword, "domain": mapKeyword,
+		}),
+		"destination": object(map[string]any{
+			"ip": mapIP, "port": mapLong, "domain": mapKeyword,
+		}),
+		"url": object(map[string]any{"full": mapWildcard, "domain": mapKeyword}),
+		"user": object(map[string]any{
+			"name": mapKeyword, "full_name": mapKeyword,
+			"group": object(map[string]any{"name": mapKeyword}),
+		}),
+		"network": object(map[string]any{"direction": mapKeyword, "transport": mapKeyword}),
+		"observer": object(map[string]any{
+			"name": mapKeyword, "vendor": mapKeyword, "serial_number": mapKeyword, "hostname": mapKeyword,
+			"ingress": object(map[string]any{
+				"interface": object(map[string]any{"name": mapKeyword}),
+			}),
+		}),
+		"rule": object(map[string]any{"name": mapKeyword}),
+		"tedalogger": object(map[string]any{
+			"nas_name": mapKeyword, "declared_brand": mapKeyword, "detected_brand": mapKeyword,
+			"url_category": mapKeyword, "action_raw": mapKeyword, "action": mapKeyword,
+			"proto_raw": mapKeyword, "direction_raw": mapKeyword, "taxonomy_version": mapKeyword,
+			"raw_charset": mapKeyword, "raw_message_b64": mapStored, "invalid_bytes": mapInteger,
+			"asset": object(map[string]any{"owner": mapKeyword, "department": mapKeyword}),
+			"user":  object(map[string]any{"department": mapKeyword}),
+		}),
+	}
+}
+
+// managedAPI bir nesne türünün okuma/yazma uçlarıdır. meta GET yanıtından _meta'yı çıkarır.
+type managedAPI struct {
+	get  func(name string) (*esapi.Response, error)
+	put  func(name string, body []byte) (*esapi.Response, error)
+	meta func(data []byte) (*managedMeta, error)
+}
+
+// installManaged nesneyi yalnızca kümede yoksa, bizim değilse ya da içeriği farklıysa yazar.
+func installManaged(kind, name string, body map[string]any, api managedAPI) error {
+	want, err := withMeta(body)
+	if err != nil {
+		return err
+	}
+
+	res, err := api.get(name)
+	if err != nil {
+		return fmt.Errorf("get %s %s: %w", kind, name, err)
+	}
+	data, readErr := readBody(res)
+	switch {
+	case readErr != nil:
+		return fmt.Errorf("get %s %s: %w", kind, name, readErr)
+	case res.StatusCode == 404:
+	case res.IsError():
+		return fmt.Errorf("get %s %s: %s", kind, name, res.String())
+	default:
+		cur, err := api.meta(data)
+		if err != nil {
+			return fmt.Errorf("parse %s %s: %w", kind, name, err)
+		}
+		if cur != nil && cur.ManagedBy == templateManagedBy {
+			if cur.Version > templateVersion {
+				log.Printf("[Templates] %s %s has newer version %d (ours %d), leaving it", kind, name, cur.Version, templateVersion)
+				return nil
+			}
+			if cur.Version == templateVersion && cur.Hash == want.meta.Hash {
+				return nil
+			}
+		}
+	}
+
+	res, err = api.put(name, want.body)
+	if err != nil {
+		return fmt.Errorf("put %s %s: %w", kind, name, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("put %s %s: %s", kind, name, res.String())
+	}
+	log.Printf("[Templates] Installed %s %s (version %d, hash %s)", kind, name, templateVersion, want.meta.Hash)
+	return nil
+}
+
+type metaBody struct {
+	body []byte
+	meta managedMeta
+}
+
+// withMeta gövdenin özetini alıp _meta'yı ekler. ILM politikasında _meta "policy" altındadır.
+func withMeta(body map[string]any) (metaBody, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return metaBody{}, err
+	}
+	sum := sha256.Sum256(raw)
+	meta := managedMeta{ManagedBy: templateManagedBy, Version: templateVersion, Hash: hex.EncodeToString(sum[:8])}
+
+	out := make(map[string]any, len(body)+2)
+	for k, v := range body {
+		out[k] = v
+	}
+	if policy, ok := body["policy"].(map[string]any); ok {
+		p := make(map[string]any, len(policy)+1)
+		for k, v := range policy {
+			p[k] = v
+		}
+		p["_meta"] = meta
+		out["policy"] = p
+	} else {
+		out["version"] = templateVersion
+		out["_meta"] = meta
+	}
+
+	raw, err = json.Marshal(out)
+	if err != nil {
+		return metaBody{}, err
+	}
+	return metaBody{body: raw, meta: meta}, nil
+}
+
+func readBody(res *esapi.Response) ([]byte, error) {
+	defer res.Body.Close()
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(res.Body)
+	return buf.Bytes(), err
+}
+
+func ilmPolicyAPI(es *esapi.API) managedAPI {
+	return managedAPI{
+		get: func(name string) (*esapi.Response, error) {
+			return es.ILM.GetLifecycle(es.ILM.GetLifecycle.WithPolicy(name))
+		},
+		put: func(name string, body []byte) (*esapi.Response, error) {
+			return es.ILM.PutLifecycle(name, es.ILM.PutLifecycle.WithBody(bytes.NewReader(body)))
+		},
+		meta: func(data []byte) (*managedMeta, error) {
+			var r map[string]struct {
+				Policy struct {
+					Meta *managedMeta `json:"_meta"`
+				} `json:"policy"`
+			}
+			if err := json.Unmarshal(data, &r); err != nil {
+				return nil, err
+			}
+			for _, p := range r {
+				return p.Policy.Meta, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func componentTemplateAPI(es *esapi.API) managedAPI {
+	return managedAPI{
+		get: func(name string) (*esapi.Response, error) {
+			return es.Cluster.GetComponentTemplate(es.Cluster.GetComponentTemplate.WithName(name))
+		},
+		put: func(name string, body []byte) (*esapi.Response, error) {
+			return es.Cluster.PutComponentTemplate(name, bytes.NewReader(body))
+		},
+		meta: func(data []byte) (*managedMeta, error) {
+			var r struct {
+				ComponentTemplates []struct {
+					ComponentTemplate struct {
+						Meta *managedMeta `json:"_meta"`
+					} `json:"component_template"`
+				} `json:"component_templates"`
+			}
+			if err := json.Unmarshal(data, &r); err != nil {
+				return nil, err
+			}
+			if len(r.ComponentTemplates) == 0 {
+				return nil, nil
+			}
+			return r.ComponentTemplates[0].ComponentTemplate.Meta, nil
+		},
+	}
+}
+
+func indexTemplateAPI(es *esapi.API) managedAPI {
+	return managedAPI{
+		get: func(name string) (*esapi.Response, error) {
+			return es.Indices.GetIndexTemplate(es.Indices.GetIndexTemplate.WithName(name))
+		},
+		put: func(name string, body []byte) (*esapi.Response, error) {
+			return es.Indices.PutIndexTemplate(name, bytes.NewReader(body))
+		},
+		meta: func(data []byte) (*managedMeta, error) {
+			var r struct {
+				IndexTemplates []struct {
+					IndexTemplate struct {
+						Meta *managedMeta `json:"_meta"`
+					} `json:"index_template"`
+				} `json:"index_templates"`
+			}
+			if err := json.Unmarshal(data, &r); err != nil {
+				return nil, err
+			}
+			if len(r.IndexTemplates) == 0 {
+				return nil, nil
+			}
+			return r.IndexTemplates[0].IndexTemplate.Meta, nil
+		},
+	}
+}
+
+func deleteIndexTemplate(es *esapi.API, name string) error {
+	res, err := es.Indices.DeleteIndexTemplate(name)
+	if err != nil {
+		return fmt.Errorf("delete index template %s: %w", name, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("delete index template %s: %s", name, res.String())
+	}
+	if !res.IsError() {
+		log.Printf("[Templates] Removed index template %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package logfetcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"tedalogger-logfetcher/config"
+)
+
+func TestIndexTemplatePatterns(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Config
+		daily []string
+		want  map[string][]string
+	}{
+		{
+			name:  "nas patterns",
+			cfg:   config.Config{IndexQuarantine: "tedalogger-quarantine", ESDataStreamPrefix: "logs-tedalogger-"},
+			daily: []string{"10_0_0_1-*-*-20*"},
+			want: map[string][]string{
+				"tedalogger-urlfilter":              {"10_0_0_1-*-*-20*", "tedalogger-quarantine"},
+				"tedalogger-urlfilter-datastream":   {"logs-tedalogger-*"},
+				"tedalogger-urlfilter-ecs-migrated": {"10_0_0_1-*-*-20*-ecs"},
+			},
+		},
+		{
+			name: "explicit patterns",
+			cfg: config.Config{
+				ESTemplatePatterns: []string{"10_0_*-*-*-20*"},
+				IndexSchemas:       []config.KeyValue{{Key: "10_1_*", Value: "ecs"}, {Key: "10_?_*", Value: "ecs"}, {Key: "10_*", Value: "legacy"}},
+				ESDataStreamPrefix: "logs-tedalogger-",
+			},
+			daily: []string{"10_0_*-*-*-20*"},
+			want: map[string][]string{
+				"tedalogger-urlfilter":              {"10_0_*-*-*-20*"},
+				"tedalogger-urlfilter-schema-1":     {"10_1_*"},
+				"tedalogger-urlfilter-schema-2":     {"10_*"},
+				"tedalogger-urlfilter-datastream":   {"logs-tedalogger-*"},
+				"tedalogger-urlfilter-ecs-migrated": {"10_0_*-*-*-20*-ecs", "10_1_*-ecs", "10_*-ecs"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string][]string{}
+			for _, spec := range indexTemplates(&tt.cfg, tt.daily) {
+				if len(spec.patterns) > 0 {
+					got[spec.name] = spec.patterns
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("patterns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSchemaTemplatePriority ilk yazılan kuralın şablonunun daha yüksek öncelik aldığını ve
+// önceliklerin diğer şablonlarla çakışmadığını sınar.
+func TestSchemaTemplatePriority(t *testing.T) {
+	cfg := &config.Config{ESTemplatePatterns: []string{"10_*"}}
+	for i := 0; i < maxIndexSchemas+5; i++ {
+		cfg.IndexSchemas = append(cfg.IndexSchemas, config.KeyValue{Key: fmt.Sprintf("10_%d_*", i), Value: "ecs"})
+	}
+	seen := map[int]string{}
+	last := 0
+	for _, spec := range indexTemplates(cfg, cfg.ESTemplatePatterns) {
+		if len(spec.patterns) == 0 {
+			continue
+		}
+		if other, ok := seen[spec.priority]; ok {
+			t.Fatalf("%s and %s share priority %d", spec.name, other, spec.priority)
+		}
+		seen[spec.priority] = spec.name
+		if strings.HasPrefix(spec.name, schemaTemplatePrefix) {
+			if last != 0 && spec.priority >= last {
+				t.Fatalf("%s priority %d not below previous rule %d", spec.name, spec.priority, last)
+			}
+			last = spec.priority
+		}
+	}
+	if n := len(seen); n != maxIndexSchemas+3 {
+		t.Fatalf("%d templates, want %d", n, maxIndexSchemas+3)
+	}
+}
+
+// TestOwnedDailyPatterns NAS desenlerinin kümedeki şablonun NAS desenleriyle birleştirildiğini
+// sınar; listenin bir kısmını bilen bir süreç diğer NAS'ların desenlerini silmemeli.
+func TestOwnedDailyPatterns(t *testing.T) {
+	templateNASMu.Lock()
+	saved := templateNAS
+	templateNAS = map[string]bool{}
+	templateNASMu.Unlock()
+	defer func() {
+		templateNASMu.Lock()
+		templateNAS = saved
+		templateNASMu.Unlock()
+	}()
+
+	existing := `{"index_templates":[{"name":"tedalogger-urlfilter","index_template":{"index_patterns":` +
+		`["10_0_0_9-*-*-20*","tedalogger-quarantine"]}}]}`
+	api := esapi.New(transportFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(existing)), Header: http.Header{}}, nil
+	}))
+
+	if !addTemplateNAS([]NAS{{Nasname: "10.0.0.1"}, {Nasname: "10.0.0.2"}}) {
+		t.Fatal("new NAS not reported")
+	}
+	if addTemplateNAS([]NAS{{Nasname: "10.0.0.1"}}) {
+		t.Fatal("known NAS reported as new")
+	}
+	got, err := ownedDailyPatterns(api, &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10_0_0_1-*-*-20*", "10_0_0_2-*-*-20*", "10_0_0_9-*-*-20*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("patterns = %v, want %v", got, want)
+	}
+
+	explicit, _ := ownedDailyPatterns(api, &config.Config{ESTemplatePatterns: []string{"fw-*"}})
+	if !reflect.DeepEqual(explicit, []string{"fw-*"}) {
+		t.Fatalf("ES_TEMPLATE_PATTERNS ignored: %v", explicit)
+	}
+}
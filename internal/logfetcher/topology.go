@@ -0,0 +1,239 @@
+// internal/logfetcher/topology.go
+
+package logfetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+	"tedalogger-logfetcher/config"
+)
+
+const (
+	retireKeep    = "keep"
+	retireDelete  = "delete"
+	retireArchive = "archive"
+)
+
+func nasRoutingKey(brand, nasName string) string {
+	r := strings.NewReplacer("{brand}", strings.ToLower(brand), "{nas}", nasName)
+	return r.Replace(config.GetConfig().RabbitMQRoutingKey)
+}
+
+// nasQueuePolicyPattern NAS kuyruklarına uyar; ".retry" ve ".dlq" kuyruklarına uymaz.
+const nasQueuePolicyPattern = "-queue$"
+
+// nasQueuePolicyPriority operatörün daha yüksek öncelikli policy'leri bizimkini ezebilir.
+const nasQueuePolicyPriority = 10
+
+func nasQueueArgs(cfg *config.Config) amqp.Table {
+	args := amqp.Table{}
+	if strings.EqualFold(cfg.RabbitMQQueueType, "quorum") {
+		args["x-queue-type"] = "quorum"
+	}
+	if cfg.RabbitMQQueueMaxLength > 0 {
+		args["x-max-length"] = int64(cfg.RabbitMQQueueMaxLength)
+		if cfg.RabbitMQQueueOverflow != "" {
+			args["x-overflow"] = cfg.RabbitMQQueueOverflow
+		}
+	}
+	if cfg.RabbitMQQueueMessageTTL > 0 {
+		args["x-message-ttl"] = int64(cfg.RabbitMQQueueMessageTTL / time.Millisecond)
+	}
+	return args
+}
+
+// declareNASTopology NAS kuyruğunu (durable, opsiyonel quorum) tanımlar ve yapılandırılmış
+// exchange'e bağlar. Kuyruk farklı argümanlarla zaten varsa (406) mevcut kuyruk olduğu gibi
+// kullanılır ve queue_args_mismatch sayılır; sınırlar ona yalnızca RABBITMQ_MANAGEMENT_URL ile
+// kurulan policy üzerinden ulaşır.
+func declareNASTopology(queue, brand, nasName string) error {
+	cfg := config.GetConfig()
+
+	err := withAdminChannel(func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclare(queue, true, false, false, false, nasQueueArgs(cfg))
+		return err
+	})
+	if isPreconditionFailed(err) {
+		incCounter("queue_args_mismatch", 1)
+		if cfg.RabbitMQManagementURL == "" {
+			log.Printf("[Topology] Queue %s exists with different arguments; configured length/TTL limits do not apply to it (set RABBITMQ_MANAGEMENT_URL to apply them as a policy): %v", queue, err)
+		} else {
+			log.Printf("[Topology] Queue %s exists with different arguments, limits apply through policy %s: %v", queue, cfg.RabbitMQPolicyName, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("queue declare %s: %w", queue, err)
+	}
+
+	if cfg.RabbitMQExchange == "" {
+		return nil
+	}
+
+	key := nasRoutingKey(brand, nasName)
+	err = withAdminChannel(func(ch *amqp.Channel) error {
+		if err := ch.ExchangeDeclare(cfg.RabbitMQExchange, cfg.RabbitMQExchangeType, true, false, false, false, nil); err != nil {
+			return err
+		}
+		return ch.QueueBind(queue, key, cfg.RabbitMQExchange, false, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("bind %s to %s (%s): %w", queue, cfg.RabbitMQExchange, key, err)
+	}
+
+	log.Printf("[Topology] Declared %s bound to %s with key %s", queue, cfg.RabbitMQExchange, key)
+	return nil
+}
+
+// nasQueuePolicy RABBITMQ_QUEUE_* sınırlarının policy tanımıdır; sınır yoksa nil.
+func nasQueuePolicy(cfg *config.Config) map[string]any {
+	def := map[string]any{}
+	if cfg.RabbitMQQueueMaxLength > 0 {
+		def["max-length"] = cfg.RabbitMQQueueMaxLength
+		if cfg.RabbitMQQueueOverflow != "" {
+			def["overflow"] = cfg.RabbitMQQueueOverflow
+		}
+	}
+	if cfg.RabbitMQQueueMessageTTL > 0 {
+		def["message-ttl"] = int64(cfg.RabbitMQQueueMessageTTL / time.Millisecond)
+	}
+	if len(def) == 0 {
+		return nil
+	}
+	return map[string]any{
+		"pattern":    nasQueuePolicyPattern,
+		"apply-to":   "queues",
+		"priority":   nasQueuePolicyPriority,
+		"definition": def,
+	}
+}
+
+// applyNASQueuePolicy sınırları yönetim API'si üzerinden policy olarak kurar; sınır yoksa
+// policy'yi siler. RABBITMQ_MANAGEMENT_URL boşsa bir şey yapmaz.
+func applyNASQueuePolicy(cfg *config.Config) error {
+	if cfg.RabbitMQManagementURL == "" {
+		return nil
+	}
+	base, err := url.Parse(strings.TrimRight(cfg.RabbitMQManagementURL, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid RABBITMQ_MANAGEMENT_URL: %w", err)
+	}
+	vhost := "/"
+	if uri, err := amqp.ParseURI(cfg.RabbitMQURL); err == nil && uri.Vhost != "" {
+		vhost = uri.Vhost
+	}
+	endpoint := fmt.Sprintf("%s/api/policies/%s/%s", base.Scheme+"://"+base.Host+base.Path,
+		url.PathEscape(vhost), url.PathEscape(cfg.RabbitMQPolicyName))
+
+	policy := nasQueuePolicy(cfg)
+	method := http.MethodDelete
+	var body io.Reader
+	if policy != nil {
+		data, _ := json.Marshal(policy)
+		method, body = http.MethodPut, bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("policy request creation error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if base.User != nil {
+		pass, _ := base.User.Password()
+		req.SetBasicAuth(base.User.Username(), pass)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("policy request error: %w", err)
+	}
+	defer resp.Body.Close()
+	if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("policy %s %s failed, status: %d, body: %s", method, cfg.RabbitMQPolicyName, resp.StatusCode, string(b))
+	}
+	if policy != nil {
+		log.Printf("[Topology] Policy %s applied to queues matching %s", cfg.RabbitMQPolicyName, nasQueuePolicyPattern)
+	}
+	return nil
+}
+
+// retireNASTopology listeden çıkan NAS'ın kuyruğunu RABBITMQ_RETIRE_MODE'a göre ele alır.
+// "archive" kuyruğu exchange'ten ayırır, yeni mesaj gelmez ama mevcutlar korunur.
+// "delete" yalnızca boş kuyrukları siler; dolu kuyruk arşivlenir.
+func retireNASTopology(queue, brand, nasName string) error {
+	cfg := config.GetConfig()
+
+	mode := strings.ToLower(cfg.RabbitMQRetireMode)
+	if mode == retireKeep || mode == "" {
+		return nil
+	}
+
+	if cfg.RabbitMQExchange != "" {
+		key := nasRoutingKey(brand, nasName)
+		err := withAdminChannel(func(ch *amqp.Channel) error {
+			return ch.QueueUnbind(queue, key, cfg.RabbitMQExchange, nil)
+		})
+		if err != nil {
+			return fmt.Errorf("unbind %s: %w", queue, err)
+		}
+	}
+
+	if mode != retireDelete {
+		log.Printf("[Topology] Archived %s (unbound, messages kept)", queue)
+		return nil
+	}
+
+	for _, q := range []string{retryQueueName(queue), queue} {
+		var purged int
+		err := withAdminChannel(func(ch *amqp.Channel) error {
+			n, err := ch.QueueDelete(q, false, true, false)
+			purged = n
+			return err
+		})
+		if isPreconditionFailed(err) {
+			log.Printf("[Topology] %s is not empty, archived instead of deleted", q)
+			continue
+		}
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("queue delete %s: %w", q, err)
+		}
+		log.Printf("[Topology] Deleted %s (%d messages)", q, purged)
+	}
+	return nil
+}
+
+// withAdminChannel işlemi havuzdan alınan geçici bir kanalda yürütür. AMQP kanal
+// seviyesindeki hatalarda kanalı kapattığı için her işlem kendi kanalını kullanır.
+func withAdminChannel(fn func(ch *amqp.Channel) error) error {
+	pch, err := getAMQPPool().acquire()
+	if err != nil {
+		return err
+	}
+	defer pch.release()
+	return fn(pch.ch)
+}
+
+func isPreconditionFailed(err error) bool {
+	if amqpErr, ok := err.(*amqp.Error); ok {
+		return amqpErr.Code == amqp.PreconditionFailed
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	if amqpErr, ok := err.(*amqp.Error); ok {
+		return amqpErr.Code == amqp.NotFound
+	}
+	return false
+}
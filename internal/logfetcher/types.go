@@ -36,6 +36,36 @@ type ParsedLog struct {
 	Hostname string `json:"hostname,omitempty"`
 
 	NASName string `json:"nas_name,omitempty"`
+
+	SrcHostname     string   `json:"src_hostname,omitempty"`
+	AssetOwner      string   `json:"asset_owner,omitempty"`
+	AssetDepartment string   `json:"asset_department,omitempty"`
+	UserDisplayName string   `json:"user_display_name,omitempty"`
+	UserDepartment  string   `json:"user_department,omitempty"`
+	UserGroups      []string `json:"user_groups,omitempty"`
+
+	RawCharset    string `json:"raw_charset,omitempty"`
+	RawMessageB64 string `json:"raw_message_b64,omitempty"`
+	InvalidBytes  int    `json:"invalid_bytes,omitempty"`
+
+	ActionRaw        string `json:"action_raw,omitempty"`
+	ProtoRaw         string `json:"proto_raw,omitempty"`
+	DirectionRaw     string `json:"direction_raw,omitempty"`
+	EventOutcome     string `json:"event_outcome,omitempty"`
+	EventAction      string `json:"event_action,omitempty"`
+	NetworkDirection string `json:"network_direction,omitempty"`
+	NetworkProtocol  string `json:"network_protocol,omitempty"`
+	TaxonomyVersion  string `json:"taxonomy_version,omitempty"`
+
+	DeclaredBrand string   `json:"declared_brand,omitempty"`
+	DetectedBrand string   `json:"detected_brand,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+
+	// SourceID kaydın kaynaktaki konumudur (SourceMessage.ID); indekslenmez, belge kimliğine girer.
+	SourceID string `json:"-"`
+	// RequireID ES_DETERMINISTIC_IDS kapalı olsa da belgeye kimlik verilmesini ister
+	// (SourceMessage.RequireID).
+	RequireID bool `json:"-"`
 }
 
 type NAS struct {